@@ -1,36 +1,180 @@
 package main
 
 import (
-	"database/sql"
+	"errors"
+	"flag"
 	"log"
+	"os"
 
 	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	v1 "github.com/ppicom/newtonian/internal/infrastructure/api/grpc/v1"
 	"github.com/ppicom/newtonian/internal/infrastructure/api/http"
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
 	"github.com/ppicom/newtonian/internal/infrastructure/db"
+	"github.com/ppicom/newtonian/internal/infrastructure/services"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	router := http.NewRouter()
-	router.Engine().Run(":8080")
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	// Initialize database connections
-	conn, err := sql.Open("mysql", "user:password@tcp(localhost:3306)/banking")
+func run() error {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		return migrate(os.Args[2:])
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	defer conn.Close()
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
-	defer rdb.Close()
+	// Initialize database connections. They are closed last, once both
+	// servers have drained their in-flight requests.
+	var rdb *redis.Client
+	if cfg.UsesRedis() {
+		rdb = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		defer rdb.Close()
+	}
+
+	storage, err := openStorage(cfg, rdb)
+	if err != nil {
+		return err
+	}
+	defer storage.close()
+
+	// Initialize use cases
+	unitOfWork := storage.unitOfWork
 
-	// Initialize repositories and controllers
-	accountRepo := db.NewAccountRepository(conn, rdb)
-	transferMoneyUseCase := usecases.NewTransferMoneyUseCase(accountRepo)
-	controller := http.NewController(transferMoneyUseCase)
+	// Without a rate provider, cross-currency transfers are refused
+	var fxRateProvider usecases.FXRateProvider
+	if cfg.Features.FXTransfers {
+		fxRateProvider = storage.fxRates
+	}
 
-	// Setup routes
+	// A single instance is already safe with the database locks alone
+	var lockManager usecases.LockManager
+	if cfg.Features.DistributedLocks {
+		lockManager = db.NewRedisLockManager(rdb, cfg.Locks.TTL, cfg.Locks.Wait)
+	}
+	transferMoneyUseCase := usecases.NewTransferMoneyUseCase(unitOfWork, fxRateProvider, lockManager)
+	getTransferUseCase := usecases.NewGetTransferUseCase(storage.transfers)
+	openAccountUseCase := usecases.NewOpenAccountUseCase(unitOfWork)
+	freezeAccountUseCase := usecases.NewFreezeAccountUseCase(unitOfWork)
+	unfreezeAccountUseCase := usecases.NewUnfreezeAccountUseCase(unitOfWork)
+	closeAccountUseCase := usecases.NewCloseAccountUseCase(unitOfWork)
+	getAccountUseCase := usecases.NewGetAccountUseCase(storage.accounts)
+	listAccountsUseCase := usecases.NewListAccountsUseCase(storage.accounts)
+	getStatementUseCase := usecases.NewGetStatementUseCase(storage.unitOfWork)
+	getBalanceAtUseCase := usecases.NewGetBalanceAtUseCase(storage.unitOfWork, storage.accountHistory)
+	depositUseCase := usecases.NewDepositUseCase(unitOfWork)
+	withdrawUseCase := usecases.NewWithdrawUseCase(unitOfWork)
+
+	registerWebhookUseCase := usecases.NewRegisterWebhookUseCase(unitOfWork, cfg.Webhooks.AllowPrivateNetworks)
+	getWebhookUseCase := usecases.NewGetWebhookUseCase(storage.webhooks)
+	listWebhooksUseCase := usecases.NewListWebhooksUseCase(storage.webhooks)
+	deleteWebhookUseCase := usecases.NewDeleteWebhookUseCase(unitOfWork)
+	listWebhookDeliveriesUseCase := usecases.NewListWebhookDeliveriesUseCase(storage.webhooks, storage.webhooks)
+	redeliverWebhookUseCase := usecases.NewRedeliverWebhookUseCase(unitOfWork)
+
+	// Without a publisher nor webhooks, events wait in the outbox
+	var pollers []poller
+	var publisher usecases.EventPublisher
+	if cfg.Events.Publisher == config.PublisherRedis {
+		publisher = services.NewRedisStreamPublisher(rdb, cfg.Events.Stream)
+	}
+	var subscribers []usecases.EventSubscriber
+	if cfg.Features.Webhooks {
+		subscribers = append(subscribers, usecases.NewScheduleWebhookDeliveriesUseCase())
+		policy := usecases.WebhookRetryPolicy{
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			MaxBackoff:  cfg.Webhooks.MaxBackoff,
+		}
+		// The lease outlasts the timeout, so a delivery is only sent again once given up on
+		lease := 2 * cfg.Webhooks.Timeout
+		sender := services.NewHTTPWebhookSender(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivateNetworks)
+		deliverWebhooksUseCase := usecases.NewDeliverWebhooksUseCase(unitOfWork, sender, policy, cfg.Webhooks.BatchSize, lease)
+		pollers = append(pollers, poller{"Delivering webhooks", deliverWebhooksUseCase.Execute, cfg.Webhooks.Interval, cfg.Webhooks.BatchSize})
+	}
+	if publisher != nil || len(subscribers) > 0 {
+		relayEventsUseCase := usecases.NewRelayEventsUseCase(storage.relayUnitOfWork, publisher, cfg.Events.BatchSize, cfg.Events.RelayLease, subscribers...)
+		pollers = append(pollers, poller{"Relaying events", relayEventsUseCase.Execute, cfg.Events.RelayInterval, cfg.Events.BatchSize})
+	}
+
+	// Setup HTTP routes
+	router := http.NewRouter(cfg.RequestTimeout)
+	controller := http.NewController(
+		transferMoneyUseCase,
+		getTransferUseCase,
+		openAccountUseCase,
+		freezeAccountUseCase,
+		unfreezeAccountUseCase,
+		closeAccountUseCase,
+		getAccountUseCase,
+		listAccountsUseCase,
+		getStatementUseCase,
+		getBalanceAtUseCase,
+		depositUseCase,
+		withdrawUseCase,
+	)
 	controller.SetupRoutes(router)
+	if cfg.Features.Webhooks {
+		webhookController := http.NewWebhookController(
+			registerWebhookUseCase,
+			getWebhookUseCase,
+			listWebhooksUseCase,
+			deleteWebhookUseCase,
+			listWebhookDeliveriesUseCase,
+			redeliverWebhookUseCase,
+		)
+		webhookController.SetupRoutes(router)
+	}
+
+	// Setup the gRPC service, with health checks and optionally reflection
+	grpcOptions := []grpc.ServerOption{grpc.UnaryInterceptor(v1.TimeoutInterceptor(cfg.RequestTimeout))}
+	if cfg.TLS.Enabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return err
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(grpcOptions...)
+	bankingServer := v1.NewBankingServer(
+		transferMoneyUseCase,
+		getTransferUseCase,
+		openAccountUseCase,
+		freezeAccountUseCase,
+		unfreezeAccountUseCase,
+		closeAccountUseCase,
+		getAccountUseCase,
+		listAccountsUseCase,
+		getStatementUseCase,
+		getBalanceAtUseCase,
+		depositUseCase,
+		withdrawUseCase,
+	)
+	v1.RegisterBankingServiceServer(grpcServer, bankingServer)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	if cfg.Features.GRPCReflection {
+		reflection.Register(grpcServer)
+	}
+
+	return serve(cfg, router, grpcServer, healthServer, pollers...)
 }
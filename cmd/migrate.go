@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
+	"github.com/ppicom/newtonian/internal/infrastructure/db"
+)
+
+const migrateUsage = "usage: server migrate up|down [steps]|status [flags]"
+
+// migrate runs the migrate subcommand, which applies or reverts the schema
+// migrations of the configured database. It takes the same flags as the
+// server.
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+
+	steps := 1
+	if action == "down" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return fmt.Errorf("steps must be a positive number, got %q", args[0])
+		}
+		steps, args = n, args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn, dialect, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "down":
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return err
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// migrateUp applies the migrations the database is missing
+func migrateUp(conn *sql.DB, dialect db.Dialect) error {
+	migrator, err := db.NewMigrator(conn, dialect)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up()
+	return err
+}
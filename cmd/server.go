@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	nethttp "net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	v1 "github.com/ppicom/newtonian/internal/infrastructure/api/grpc/v1"
+	"github.com/ppicom/newtonian/internal/infrastructure/api/http"
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// serve runs the HTTP and gRPC servers side by side until SIGINT or SIGTERM,
+// or until one of them fails, and then stops both gracefully. The pollers run
+// alongside them, and serve returns once they have stopped too, so none
+// is left using the storage as it closes.
+func serve(cfg config.Config, router *http.Router, grpcServer *grpc.Server, healthServer *health.Server, pollers ...poller) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var polling sync.WaitGroup
+	pollCtx, stopPolling := context.WithCancel(ctx)
+	defer polling.Wait()
+	defer stopPolling()
+	for _, p := range pollers {
+		polling.Go(func() { p.run(pollCtx) })
+	}
+
+	httpAddr, grpcAddr := cfg.HTTP.Addr, cfg.GRPC.Addr
+	listener, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		return err
+	}
+
+	httpServer := &nethttp.Server{Addr: httpAddr, Handler: router.Engine()}
+	errs := make(chan error, 2)
+
+	go func() {
+		log.Printf("HTTP server listening on %s", httpAddr)
+		var err error
+		if cfg.TLS.Enabled() {
+			err = httpServer.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if !errors.Is(err, nethttp.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	go func() {
+		log.Printf("gRPC server listening on %s", grpcAddr)
+		if err := grpcServer.Serve(listener); err != nil {
+			errs <- err
+		}
+	}()
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(v1.BankingService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Print("Shutting down")
+	case serveErr = <-errs:
+		log.Printf("Server failed, shutting down: %v", serveErr)
+	}
+
+	// Stop taking traffic, then wait for in-flight transfers to finish
+	healthServer.Shutdown()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server did not drain in time: %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		log.Print("gRPC server did not drain in time")
+		grpcServer.Stop()
+	}
+
+	return serveErr
+}
+
+// poller runs a use case working through a backlog in batches, such as
+// the event relay
+type poller struct {
+	name      string
+	execute   func(ctx context.Context) (int, error)
+	interval  time.Duration
+	batchSize int
+}
+
+// run executes the use case until ctx is done. It keeps going while it gets
+// full batches and otherwise waits interval, also after a failure.
+func (p poller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := p.execute(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("%s failed: %v", p.name, err)
+		}
+		if err == nil && done == p.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
+	"github.com/ppicom/newtonian/internal/infrastructure/db"
+	"github.com/ppicom/newtonian/internal/infrastructure/memory"
+	"github.com/redis/go-redis/v9"
+)
+
+// storage holds the repositories the use cases run on
+type storage struct {
+	unitOfWork usecases.UnitOfWork
+	// relayUnitOfWork runs the transactions of the event relay, which need
+	// no more isolation than read committed, as one relay runs at a time
+	relayUnitOfWork usecases.UnitOfWork
+	accounts        accountRepository
+	// accountHistory is nil unless accounts are stored as their events
+	accountHistory usecases.AccountHistory
+	transfers      usecases.TransferRepository
+	fxRates        usecases.FXRateProvider
+	webhooks       webhookRepository
+	close          func() error
+}
+
+type accountRepository interface {
+	usecases.AccountRepository
+	usecases.AccountLister
+	usecases.BalanceHistory
+}
+
+type webhookRepository interface {
+	usecases.WebhookRepository
+	usecases.WebhookDeliveryRepository
+}
+
+// sqlDrivers maps the configured drivers to the database/sql driver and SQL dialect they use
+var sqlDrivers = map[string]struct {
+	name    string
+	dialect db.Dialect
+}{
+	config.DriverMySQL:    {"mysql", db.MySQL},
+	config.DriverPostgres: {"pgx", db.Postgres},
+}
+
+// openStorage connects to the storage selected by the configuration
+func openStorage(cfg config.Config, rdb *redis.Client) (*storage, error) {
+	if cfg.Database.Driver == config.DriverMemory {
+		store := memory.NewStore()
+		return &storage{
+			unitOfWork:      memory.NewUnitOfWork(store),
+			relayUnitOfWork: memory.NewUnitOfWork(store),
+			accounts:        memory.NewAccountRepository(store),
+			transfers:       memory.NewTransferRepository(store),
+			fxRates:         memory.NewFXRepository(),
+			webhooks:        memory.NewWebhookRepository(store),
+			close:           func() error { return nil },
+		}, nil
+	}
+
+	conn, dialect, err := openDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// A single SQLite node has no other instance to share a cache with, nor
+	// to coordinate a migration with, so it migrates its own file
+	if dialect == db.SQLite {
+		rdb = nil
+		if err := migrateUp(conn, dialect); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	// Switching how accounts are stored would lose them, as nothing converts them
+	events := cfg.Database.AccountStorage == config.AccountStorageEvents
+	if err := db.CheckAccountStorage(context.Background(), conn, dialect, events); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("database.account_storage is %s: %w", cfg.Database.AccountStorage, err)
+	}
+	return newSQLStorage(conn, dialect, cfg.Database, rdb, cfg.Redis.CacheTTL), nil
+}
+
+// openDatabase connects to the SQL database selected by the configuration
+func openDatabase(cfg config.Config) (*sql.DB, db.Dialect, error) {
+	if cfg.Database.Driver == config.DriverSQLite {
+		conn, err := db.OpenSQLite(cfg.Database.Path)
+		return conn, db.SQLite, err
+	}
+
+	driver, ok := sqlDrivers[cfg.Database.Driver]
+	if !ok {
+		return nil, "", fmt.Errorf("database driver %q is not a SQL database", cfg.Database.Driver)
+	}
+	conn, err := sql.Open(driver.name, cfg.Database.DSN)
+	if err != nil {
+		return nil, "", err
+	}
+	conn.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	conn.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	conn.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	return conn, driver.dialect, nil
+}
+
+// newSQLStorage builds the repositories of a SQL database. Accounts stored
+// as their state are cached in rdb unless it is nil.
+func newSQLStorage(conn *sql.DB, dialect db.Dialect, cfg config.DatabaseConfig, rdb *redis.Client, cacheTTL time.Duration) *storage {
+	var accountRepo db.AccountStore = db.NewAccountRepository(conn, dialect, rdb, cacheTTL)
+	var accountHistory usecases.AccountHistory
+	if cfg.AccountStorage == config.AccountStorageEvents {
+		events := db.NewEventSourcedAccountRepository(conn, dialect, cfg.SnapshotEvery)
+		accountRepo, accountHistory = events, events
+	}
+	transferRepo := db.NewTransferRepository(conn, dialect)
+	fxRepo := db.NewFXRepository(conn, dialect)
+	idempotencyRepo := db.NewIdempotencyRepository(conn, dialect)
+	outboxRepo := db.NewOutboxRepository(conn, dialect)
+	webhookRepo := db.NewWebhookRepository(conn, dialect)
+	return &storage{
+		unitOfWork:      db.NewUnitOfWork(conn, db.Locking(cfg.Locking), accountRepo, transferRepo, fxRepo, idempotencyRepo, outboxRepo, webhookRepo),
+		relayUnitOfWork: db.NewUnitOfWork(conn, db.Optimistic, accountRepo, transferRepo, fxRepo, idempotencyRepo, outboxRepo, webhookRepo),
+		accounts:        accountRepo,
+		accountHistory:  accountHistory,
+		transfers:       transferRepo,
+		fxRates:         fxRepo,
+		webhooks:        webhookRepo,
+		close:           conn.Close,
+	}
+}
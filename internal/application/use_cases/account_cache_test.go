@@ -0,0 +1,135 @@
+package usecases_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/db"
+	"github.com/stretchr/testify/require"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+func cachedAccount(t *testing.T, id string) *banking.Account {
+	t.Helper()
+	data, err := testRedis.Get(t.Context(), "account:"+id).Bytes()
+	if err != nil {
+		return nil
+	}
+
+	var account banking.Account
+	require.NoError(t, json.Unmarshal(data, &account))
+	return &account
+}
+
+func TestAccountCache_IgnoredByTransfers(t *testing.T) {
+	requireDatabase(t)
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+
+	// A stale cache entry must not be trusted for the balance check
+	stale, err := json.Marshal(&banking.Account{ID: "acc1", Balance: 1000, Currency: "EUR", Status: banking.AccountActive})
+	require.NoError(t, err)
+	require.NoError(t, testRedis.Set(t.Context(), "account:acc1", stale, 0).Err())
+
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(500, "EUR"))
+	require.ErrorIs(t, err, banking.ErrInsufficientFunds)
+	require.Equal(t, 100, getAccountBalance(t, "acc1"))
+}
+
+func TestAccountCache_IgnoredByStatements(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	from := time.Now().Add(-time.Minute)
+
+	// A stale cache entry must not be the closing balance of the entries
+	stale, err := json.Marshal(&banking.Account{ID: "acc1", Balance: 1000, Currency: "EUR", Status: banking.AccountActive})
+	require.NoError(t, err)
+	require.NoError(t, testRedis.Set(t.Context(), "account:acc1", stale, 0).Err())
+
+	statement, err := usecases.NewGetStatementUseCase(testBackend.unitOfWork).Execute(t.Context(), "acc1", from, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, 100, statement.ClosingBalance)
+}
+
+func TestAccountCache_UpdatedAfterCommit(t *testing.T) {
+	requireDatabase(t)
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+
+	err := newUnitOfWork().WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(t.Context(), "acc1")
+		require.NoError(t, err)
+		require.NoError(t, banking.Withdraw(account, 30))
+		require.NoError(t, repos.Accounts().Save(t.Context(), account))
+
+		// Nothing is cached until the transaction commits
+		require.Nil(t, cachedAccount(t, "acc1"))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 70, cachedAccount(t, "acc1").Balance)
+
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(20, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 50, cachedAccount(t, "acc1").Balance)
+	require.Equal(t, 70, cachedAccount(t, "acc2").Balance)
+}
+
+func TestAccountCache_ReplacesUnversionedEntries(t *testing.T) {
+	requireDatabase(t)
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+
+	// Cached before accounts had a version
+	unversioned := `{"ID":"acc1","Balance":1000,"Currency":"EUR","Status":"active"}`
+	require.NoError(t, testRedis.Set(t.Context(), "account:acc1", unversioned, 0).Err())
+
+	_, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(20, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 80, cachedAccount(t, "acc1").Balance)
+}
+
+func TestAccountCache_InvalidatedOnRollback(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	repo := db.NewAccountRepository(testDB, testDialect, testRedis, testConfig.Redis.CacheTTL)
+
+	// Warm the cache outside a transaction
+	_, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	require.Equal(t, 100, cachedAccount(t, "acc1").Balance)
+
+	errAborted := errors.New("aborted")
+	err = newUnitOfWork().WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(t.Context(), "acc1")
+		require.NoError(t, err)
+		require.NoError(t, banking.Withdraw(account, 30))
+		require.NoError(t, repos.Accounts().Save(t.Context(), account))
+		return errAborted
+	})
+	require.ErrorIs(t, err, errAborted)
+
+	require.Nil(t, cachedAccount(t, "acc1"))
+	account, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	require.Equal(t, 100, account.Balance)
+}
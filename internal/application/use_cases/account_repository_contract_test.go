@@ -0,0 +1,271 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAccountRepositoryContract checks every available backend behaves the
+// same way the use cases rely on
+func TestAccountRepositoryContract(t *testing.T) {
+	for driver, newBackend := range backends() {
+		t.Run(driver, func(t *testing.T) {
+			t.Run("find unknown account", func(t *testing.T) {
+				repo := newBackend(t).accounts
+
+				_, err := repo.Find(t.Context(), "unknown")
+				require.ErrorIs(t, err, banking.ErrAccountNotFound)
+			})
+
+			t.Run("save and find", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				account := &banking.Account{ID: "acc1", Owner: "alice", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+				require.NoError(t, repo.Save(t.Context(), account))
+
+				found, err := repo.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, "alice", found.Owner)
+				require.Equal(t, 100, found.Balance)
+				require.Equal(t, "EUR", found.Currency)
+				require.Equal(t, banking.AccountActive, found.Status)
+			})
+
+			t.Run("save writes pending ledger entries", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				account := &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}
+				require.NoError(t, banking.Deposit(account, 50))
+				require.NoError(t, repo.Save(t.Context(), account))
+				require.Empty(t, account.PendingEntries())
+
+				since := time.Now()
+				require.NoError(t, banking.Deposit(account, 25))
+				require.NoError(t, repo.Save(t.Context(), account))
+
+				entries, err := repo.FindLedgerEntries(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Len(t, entries, 2)
+				require.Equal(t, 50, entries[0].Amount)
+
+				entries, err = repo.FindLedgerEntriesSince(t.Context(), "acc1", since)
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+				require.Equal(t, 25, entries[0].Amount)
+			})
+
+			t.Run("save records when the account was opened", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				before := time.Now()
+				account := &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}
+				require.NoError(t, repo.Save(t.Context(), account))
+				after := time.Now()
+				require.NoError(t, banking.Deposit(account, 25))
+				require.NoError(t, repo.Save(t.Context(), account))
+
+				opened, err := repo.FindOpenedAt(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.WithinRange(t, opened, before.Add(-time.Millisecond), after.Add(time.Millisecond), "a later save keeps it")
+
+				_, err = repo.FindOpenedAt(t.Context(), "unknown")
+				require.ErrorIs(t, err, banking.ErrAccountNotFound)
+			})
+
+			t.Run("list pages by id", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				for _, id := range []string{"acc3", "acc1", "acc2"} {
+					require.NoError(t, repo.Save(t.Context(), &banking.Account{ID: id, Currency: "EUR", Status: banking.AccountActive}))
+				}
+
+				page, err := repo.List(t.Context(), "", 2)
+				require.NoError(t, err)
+				require.Len(t, page, 2)
+				require.Equal(t, "acc1", page[0].ID)
+				require.Equal(t, "acc2", page[1].ID)
+
+				page, err = repo.List(t.Context(), "acc2", 2)
+				require.NoError(t, err)
+				require.Len(t, page, 1)
+				require.Equal(t, "acc3", page[0].ID)
+			})
+
+			t.Run("save of a stale account", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+				require.NoError(t, repo.Save(t.Context(), account))
+				require.Equal(t, 1, account.Version)
+
+				stale, err := repo.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.NoError(t, banking.Deposit(account, 10))
+				require.NoError(t, repo.Save(t.Context(), account))
+				require.Equal(t, 2, account.Version)
+
+				require.NoError(t, banking.Deposit(stale, 20))
+				require.ErrorIs(t, repo.Save(t.Context(), stale), usecases.ErrConcurrentModification)
+
+				duplicate := &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}
+				require.ErrorIs(t, repo.Save(t.Context(), duplicate), usecases.ErrConcurrentModification)
+
+				found, err := repo.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, 110, found.Balance)
+				require.Equal(t, 2, found.Version)
+			})
+
+			t.Run("fenced save of a lost lock", func(t *testing.T) {
+				repo := newBackend(t).accounts
+				account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+				require.NoError(t, repo.SaveFenced(t.Context(), account, 1))
+
+				// The holder of token 2 took the account over once lock 1 expired
+				require.NoError(t, banking.Deposit(account, 10))
+				require.NoError(t, repo.SaveFenced(t.Context(), account, 2))
+
+				// The holder of lock 1 is rejected, even with a fresh read
+				stale, err := repo.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.NoError(t, banking.Deposit(stale, 20))
+				require.ErrorIs(t, repo.SaveFenced(t.Context(), stale, 1), usecases.ErrLockLost)
+
+				found, err := repo.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, 110, found.Balance)
+				require.NoError(t, banking.Deposit(found, 5))
+				require.NoError(t, repo.SaveFenced(t.Context(), found, 3))
+			})
+
+			t.Run("commit", func(t *testing.T) {
+				b := newBackend(t)
+				committed := false
+				err := b.unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+					repos.AfterCommit(func() { committed = true })
+					if err := repos.Accounts().Save(t.Context(), &banking.Account{ID: "acc1", Balance: 10, Currency: "EUR", Status: banking.AccountActive}); err != nil {
+						return err
+					}
+
+					// A transaction reads its own writes
+					account, err := repos.Accounts().Find(t.Context(), "acc1")
+					if err != nil {
+						return err
+					}
+					require.Equal(t, 10, account.Balance)
+					return nil
+				})
+				require.NoError(t, err)
+				require.True(t, committed)
+
+				account, err := b.accounts.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, 10, account.Balance)
+			})
+
+			t.Run("rollback", func(t *testing.T) {
+				b := newBackend(t)
+				require.NoError(t, b.accounts.Save(t.Context(), &banking.Account{ID: "acc1", Balance: 10, Currency: "EUR", Status: banking.AccountActive}))
+
+				errAbort := errors.New("abort")
+				committed := false
+				err := b.unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+					repos.AfterCommit(func() { committed = true })
+					account, err := repos.Accounts().Find(t.Context(), "acc1")
+					if err != nil {
+						return err
+					}
+					if err := banking.Deposit(account, 90); err != nil {
+						return err
+					}
+					if err := repos.Accounts().Save(t.Context(), account); err != nil {
+						return err
+					}
+					return errAbort
+				})
+				require.ErrorIs(t, err, errAbort)
+				require.False(t, committed)
+
+				account, err := b.accounts.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, 10, account.Balance)
+
+				entries, err := b.accounts.FindLedgerEntries(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Empty(t, entries)
+			})
+
+			t.Run("canceled transaction", func(t *testing.T) {
+				b := newBackend(t)
+				require.NoError(t, b.accounts.Save(t.Context(), &banking.Account{ID: "acc1", Balance: 10, Currency: "EUR", Status: banking.AccountActive}))
+
+				ctx, cancel := context.WithCancel(t.Context())
+				committed := false
+				err := b.unitOfWork.WithinTx(ctx, func(repos usecases.Repositories) error {
+					repos.AfterCommit(func() { committed = true })
+					account, err := repos.Accounts().Find(ctx, "acc1")
+					if err != nil {
+						return err
+					}
+					if err := banking.Deposit(account, 90); err != nil {
+						return err
+					}
+					cancel()
+					return repos.Accounts().Save(ctx, account)
+				})
+				require.ErrorIs(t, err, context.Canceled)
+				require.False(t, committed)
+
+				account, err := b.accounts.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, 10, account.Balance)
+			})
+
+			t.Run("concurrent transactions", func(t *testing.T) {
+				b := newBackend(t)
+				require.NoError(t, b.accounts.Save(t.Context(), &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}))
+
+				const deposits = 20
+				var wg sync.WaitGroup
+				errs := make(chan error, deposits)
+				for i := 0; i < deposits; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						errs <- depositWithRetry(t.Context(), b.unitOfWork, "acc1", 5)
+					}()
+				}
+				wg.Wait()
+				close(errs)
+				for err := range errs {
+					require.NoError(t, err)
+				}
+
+				account, err := b.accounts.Find(t.Context(), "acc1")
+				require.NoError(t, err)
+				require.Equal(t, deposits*5, account.Balance)
+			})
+		})
+	}
+}
+
+// depositWithRetry deposits in a transaction of its own, retrying it on conflicts
+func depositWithRetry(ctx context.Context, unitOfWork usecases.UnitOfWork, id string, amount int) error {
+	for {
+		err := unitOfWork.WithinTx(ctx, func(repos usecases.Repositories) error {
+			account, err := repos.Accounts().Find(ctx, id)
+			if err != nil {
+				return err
+			}
+			if err := banking.Deposit(account, amount); err != nil {
+				return err
+			}
+			return repos.Accounts().Save(ctx, account)
+		})
+		if !errors.Is(err, usecases.ErrTransactionConflict) && !errors.Is(err, usecases.ErrConcurrentModification) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type FreezeAccountUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *FreezeAccountUseCase) Execute(ctx context.Context, id, reason, actor string) (*banking.Account, error) {
+	return updateAccount(ctx, uc.unitOfWork, id, func(account *banking.Account) error {
+		return banking.Freeze(account, reason, actor)
+	})
+}
+
+func NewFreezeAccountUseCase(unitOfWork UnitOfWork) *FreezeAccountUseCase {
+	return &FreezeAccountUseCase{unitOfWork: unitOfWork}
+}
+
+type UnfreezeAccountUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *UnfreezeAccountUseCase) Execute(ctx context.Context, id, reason, actor string) (*banking.Account, error) {
+	return updateAccount(ctx, uc.unitOfWork, id, func(account *banking.Account) error {
+		return banking.Unfreeze(account, reason, actor)
+	})
+}
+
+func NewUnfreezeAccountUseCase(unitOfWork UnitOfWork) *UnfreezeAccountUseCase {
+	return &UnfreezeAccountUseCase{unitOfWork: unitOfWork}
+}
+
+type CloseAccountUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *CloseAccountUseCase) Execute(ctx context.Context, id, reason, actor string) (*banking.Account, error) {
+	return updateAccount(ctx, uc.unitOfWork, id, func(account *banking.Account) error {
+		return banking.Close(account, reason, actor)
+	})
+}
+
+func NewCloseAccountUseCase(unitOfWork UnitOfWork) *CloseAccountUseCase {
+	return &CloseAccountUseCase{unitOfWork: unitOfWork}
+}
+
+// updateAccount applies change to the account in its own transaction, retrying on conflicts
+func updateAccount(ctx context.Context, unitOfWork UnitOfWork, id string, change func(*banking.Account) error) (*banking.Account, error) {
+	var account *banking.Account
+	err := retry(ctx, func() error {
+		return unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			var err error
+			if account, err = repos.Accounts().Find(ctx, id); err != nil {
+				return err
+			}
+
+			if err := change(account); err != nil {
+				return err
+			}
+			return repos.Accounts().Save(ctx, account)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
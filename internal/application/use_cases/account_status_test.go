@@ -0,0 +1,55 @@
+package usecases_test
+
+import (
+	"testing"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountLifecycleUseCases(t *testing.T) {
+	transferMoney, cleanup := setupTest(t)
+	defer cleanup()
+
+	unitOfWork := testBackend.unitOfWork
+	openAccount := usecases.NewOpenAccountUseCase(unitOfWork)
+	freezeAccount := usecases.NewFreezeAccountUseCase(unitOfWork)
+	unfreezeAccount := usecases.NewUnfreezeAccountUseCase(unitOfWork)
+	closeAccount := usecases.NewCloseAccountUseCase(unitOfWork)
+
+	account, err := openAccount.Execute(t.Context(), "alice", "EUR", "clerk")
+	require.NoError(t, err)
+	require.Equal(t, banking.AccountActive, account.Status)
+	createAccount(t, "funding", 100)
+
+	_, err = transferMoney.Execute(t.Context(), "funding", account.ID, banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	_, err = freezeAccount.Execute(t.Context(), account.ID, "fraud check", "ops")
+	require.NoError(t, err)
+
+	_, err = transferMoney.Execute(t.Context(), "funding", account.ID, banking.NewMoney(30, "EUR"))
+	require.ErrorIs(t, err, banking.ErrAccountFrozen)
+
+	_, err = closeAccount.Execute(t.Context(), account.ID, "customer request", "ops")
+	require.ErrorIs(t, err, banking.ErrAccountNotEmpty)
+
+	_, err = unfreezeAccount.Execute(t.Context(), account.ID, "cleared", "ops")
+	require.NoError(t, err)
+
+	_, err = transferMoney.Execute(t.Context(), account.ID, "funding", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	closed, err := closeAccount.Execute(t.Context(), account.ID, "customer request", "ops")
+	require.NoError(t, err)
+	require.Equal(t, banking.AccountClosed, closed.Status)
+
+	if testDB == nil {
+		return
+	}
+	var changes int
+	err = testDB.QueryRow(testDialect.Rebind("SELECT COUNT(*) FROM account_status_changes WHERE account_id = ?"), account.ID).Scan(&changes)
+	require.NoError(t, err)
+	require.Equal(t, 4, changes, "opened, frozen, unfrozen and closed")
+}
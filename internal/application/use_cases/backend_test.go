@@ -0,0 +1,183 @@
+package usecases_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
+	"github.com/ppicom/newtonian/internal/infrastructure/db"
+	"github.com/ppicom/newtonian/internal/infrastructure/memory"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+// backend is the storage the use cases run on in a test
+type backend struct {
+	unitOfWork usecases.UnitOfWork
+	accounts   accountRepository
+	// accountHistory is nil unless accounts are stored as their events
+	accountHistory usecases.AccountHistory
+	transfers      usecases.TransferRepository
+	fxRates        usecases.FXRateProvider
+	webhooks       webhookRepository
+	setRate        func(rate banking.Rate) error
+}
+
+type accountRepository interface {
+	usecases.AccountRepository
+	usecases.AccountLister
+	usecases.BalanceHistory
+	FindLedgerEntries(ctx context.Context, accountID string) ([]banking.LedgerEntry, error)
+}
+
+type webhookRepository interface {
+	usecases.WebhookRepository
+	usecases.WebhookDeliveryRepository
+}
+
+// lockingModes are the ways SQL backends can lock accounts, which the
+// use cases must work with alike
+var lockingModes = []db.Locking{db.Pessimistic, db.Optimistic}
+
+// backends lists the backends available to the tests, by backendName. SQL
+// backends are listed with each of the lockingModes.
+func backends() map[string]func(t testing.TB) *backend {
+	available := map[string]func(t testing.TB) *backend{
+		config.DriverMemory: newMemoryBackend,
+	}
+	sqlBackends := map[string]func(t testing.TB, locking db.Locking) *backend{
+		config.DriverSQLite: newSQLiteBackend,
+	}
+	if testDB != nil {
+		sqlBackends[testConfig.Database.Driver] = newSQLBackend
+	}
+	for driver, newBackend := range sqlBackends {
+		for _, locking := range lockingModes {
+			available[backendName(driver, locking)] = func(t testing.TB) *backend {
+				return newBackend(t, locking)
+			}
+		}
+	}
+	return available
+}
+
+// backendName names the backend of a driver locking accounts as given,
+// which only SQL drivers tell apart
+func backendName(driver string, locking db.Locking) string {
+	if driver == config.DriverMemory {
+		return driver
+	}
+	return driver + "/" + string(locking)
+}
+
+func newMemoryBackend(t testing.TB) *backend {
+	store := memory.NewStore()
+	fxRepo := memory.NewFXRepository()
+	return &backend{
+		unitOfWork: memory.NewUnitOfWork(store),
+		accounts:   memory.NewAccountRepository(store),
+		transfers:  memory.NewTransferRepository(store),
+		fxRates:    fxRepo,
+		webhooks:   memory.NewWebhookRepository(store),
+		setRate: func(rate banking.Rate) error {
+			fxRepo.SetRate(rate)
+			return nil
+		},
+	}
+}
+
+// newSQLiteBackend keeps its database in a file of its own, removed with the test
+func newSQLiteBackend(t testing.TB, locking db.Locking) *backend {
+	t.Helper()
+	conn, err := db.OpenSQLite(filepath.Join(t.TempDir(), "banking.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	migrator, err := db.NewMigrator(conn, db.SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	return newSQLBackendOn(conn, db.SQLite, nil, locking)
+}
+
+// newSQLBackend empties the test database and cache first
+func newSQLBackend(t testing.TB, locking db.Locking) *backend {
+	t.Helper()
+	for _, table := range testTables {
+		_, err := testDB.Exec("DELETE FROM " + table)
+		require.NoError(t, err)
+	}
+	// The relay lease is a single row, which expires rather than goes
+	_, err := testDB.Exec(testDialect.Rebind("UPDATE relay_lease SET holder = '', expires_at = ?"), time.Unix(0, 0).UTC())
+	require.NoError(t, err)
+	require.NoError(t, testRedis.FlushAll(t.Context()).Err())
+	return newSQLBackendOn(testDB, testDialect, testRedis, locking)
+}
+
+// newSQLBackendOn stores accounts as configured, as their state or their events
+func newSQLBackendOn(conn *sql.DB, dialect db.Dialect, rdb *redis.Client, locking db.Locking) *backend {
+	accountRepo := newAccountStore(conn, dialect, rdb)
+	transferRepo := db.NewTransferRepository(conn, dialect)
+	fxRepo := db.NewFXRepository(conn, dialect)
+	webhookRepo := db.NewWebhookRepository(conn, dialect)
+	var accountHistory usecases.AccountHistory
+	if events, ok := accountRepo.(*db.EventSourcedAccountRepository); ok {
+		accountHistory = events
+	}
+	return &backend{
+		unitOfWork: db.NewUnitOfWork(
+			conn,
+			locking,
+			accountRepo,
+			transferRepo,
+			fxRepo,
+			db.NewIdempotencyRepository(conn, dialect),
+			db.NewOutboxRepository(conn, dialect),
+			webhookRepo,
+		),
+		accounts:       accountRepo,
+		accountHistory: accountHistory,
+		transfers:      transferRepo,
+		fxRates:        fxRepo,
+		webhooks:       webhookRepo,
+		setRate: func(rate banking.Rate) error {
+			_, err := conn.Exec(dialect.Rebind("INSERT INTO fx_rates (base, quote, rate, spread_bps, updated_at) VALUES (?, ?, ?, ?, ?)"),
+				rate.Base, rate.Quote, rate.Value.FloatString(12), rate.SpreadBps, rate.AsOf)
+			return err
+		},
+	}
+}
+
+func newUnitOfWork() *db.UnitOfWork {
+	return db.NewUnitOfWork(
+		testDB,
+		db.Locking(testConfig.Database.Locking),
+		newAccountStore(testDB, testDialect, testRedis),
+		db.NewTransferRepository(testDB, testDialect),
+		db.NewFXRepository(testDB, testDialect),
+		db.NewIdempotencyRepository(testDB, testDialect),
+		db.NewOutboxRepository(testDB, testDialect),
+		db.NewWebhookRepository(testDB, testDialect),
+	)
+}
+
+func newAccountStore(conn *sql.DB, dialect db.Dialect, rdb *redis.Client) db.AccountStore {
+	if testConfig.Database.AccountStorage == config.AccountStorageEvents {
+		return db.NewEventSourcedAccountRepository(conn, dialect, testConfig.Database.SnapshotEvery)
+	}
+	return db.NewAccountRepository(conn, dialect, rdb, testConfig.Redis.CacheTTL)
+}
+
+// requireDatabase skips tests of behavior only the SQL and Redis backends have
+func requireDatabase(t *testing.T) {
+	t.Helper()
+	if testDB == nil {
+		t.Skip("needs a SQL database and Redis; run with BANKING_DATABASE_DRIVER=mysql or postgres")
+	}
+}
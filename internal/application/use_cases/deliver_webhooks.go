@@ -0,0 +1,219 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// WebhookSender posts a delivery to its webhook. It fails unless the webhook
+// acknowledged it.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery) error
+}
+
+// webhookPayload is the body posted to webhooks
+type webhookPayload struct {
+	EventID    string          `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// ScheduleWebhookDeliveriesUseCase is the EventSubscriber adding a delivery
+// of each relayed event to every webhook subscribed to it
+type ScheduleWebhookDeliveriesUseCase struct{}
+
+// HandleEvent skips the webhooks the event is already on its way to, as
+// the relay can hand it over more than once
+func (uc *ScheduleWebhookDeliveriesUseCase) HandleEvent(ctx context.Context, repos Repositories, message OutboxMessage) error {
+	webhooks, err := repos.Webhooks().ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(webhookPayload{
+		EventID:    message.EventID,
+		EventType:  message.EventType,
+		OccurredAt: message.OccurredAt,
+		Data:       message.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Subscribes(message.EventType) {
+			continue
+		}
+
+		id := requestHash(webhook.ID, message.EventID)[:32]
+		_, err := repos.WebhookDeliveries().FindDelivery(ctx, id)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrDeliveryNotFound) {
+			return err
+		}
+
+		now := time.Now().UTC()
+		err = repos.WebhookDeliveries().SaveDelivery(ctx, &WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhook.ID,
+			EventID:       message.EventID,
+			EventType:     message.EventType,
+			Payload:       payload,
+			Status:        DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewScheduleWebhookDeliveriesUseCase() *ScheduleWebhookDeliveriesUseCase {
+	return &ScheduleWebhookDeliveriesUseCase{}
+}
+
+// WebhookRetryPolicy is how often, and how far apart, a delivery is attempted
+type WebhookRetryPolicy struct {
+	// MaxAttempts is how many attempts a delivery gets before it is dead
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every other one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// backoff returns how long to wait after attempts failed attempts
+func (p WebhookRetryPolicy) backoff(attempts int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < attempts && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.MaxBackoff)
+}
+
+type DeliverWebhooksUseCase struct {
+	unitOfWork UnitOfWork
+	sender     WebhookSender
+	policy     WebhookRetryPolicy
+	batchSize  int
+	lease      time.Duration
+}
+
+// claimedDelivery is a delivery along with the webhook it goes to
+type claimedDelivery struct {
+	webhook  *Webhook
+	delivery *WebhookDelivery
+}
+
+// Execute sends a batch of the due deliveries side by side, and returns how
+// many it attempted. The batch is claimed for the lease first, so no other
+// instance sends it meanwhile, and no transaction stays open while the
+// webhooks answer. A delivery whose outcome could not be recorded is sent
+// again once its lease is over: receivers tell the copies apart by event ID.
+func (uc *DeliverWebhooksUseCase) Execute(ctx context.Context) (int, error) {
+	claimed, err := uc.claim(ctx)
+	if err != nil || len(claimed) == 0 {
+		return 0, err
+	}
+
+	results := make([]error, len(claimed))
+	var wg sync.WaitGroup
+	for i, c := range claimed {
+		wg.Go(func() {
+			results[i] = uc.sender.Send(ctx, c.webhook, c.delivery)
+		})
+	}
+	wg.Wait()
+
+	// Leave the deliveries interrupted by a shutdown to the end of their lease
+	if err := ctx.Err(); err != nil {
+		return len(claimed), err
+	}
+	return len(claimed), uc.record(ctx, claimed, results)
+}
+
+// claim pushes the next attempt of the due deliveries past the lease. The
+// deliveries to webhooks deleted meanwhile are dead.
+func (uc *DeliverWebhooksUseCase) claim(ctx context.Context) ([]claimedDelivery, error) {
+	var claimed []claimedDelivery
+	err := retry(ctx, func() error {
+		return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			claimed = nil
+			now := time.Now().UTC()
+			due, err := repos.WebhookDeliveries().DueDeliveries(ctx, now, uc.batchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, delivery := range due {
+				webhook, err := repos.Webhooks().FindWebhook(ctx, delivery.WebhookID)
+				switch {
+				case errors.Is(err, ErrWebhookNotFound):
+					delivery.Status, delivery.LastError = DeliveryDead, err.Error()
+				case err != nil:
+					return err
+				default:
+					delivery.NextAttemptAt = now.Add(uc.lease)
+					claimed = append(claimed, claimedDelivery{webhook: webhook, delivery: delivery})
+				}
+
+				delivery.UpdatedAt = now
+				if err := repos.WebhookDeliveries().SaveDelivery(ctx, delivery); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	return claimed, err
+}
+
+// record saves the outcome of each attempt. A failed delivery is attempted
+// again after a backoff, until it runs out of attempts and is dead.
+func (uc *DeliverWebhooksUseCase) record(ctx context.Context, claimed []claimedDelivery, results []error) error {
+	return retry(ctx, func() error {
+		return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			now := time.Now().UTC()
+			for i, c := range claimed {
+				delivery := *c.delivery
+				delivery.Attempts++
+				delivery.UpdatedAt = now
+				switch {
+				case results[i] == nil:
+					delivery.Status, delivery.LastError = DeliveryDelivered, ""
+				case delivery.Attempts >= uc.policy.MaxAttempts:
+					delivery.Status, delivery.LastError = DeliveryDead, results[i].Error()
+				default:
+					delivery.LastError = results[i].Error()
+					delivery.NextAttemptAt = now.Add(uc.policy.backoff(delivery.Attempts))
+				}
+
+				if err := repos.WebhookDeliveries().SaveDelivery(ctx, &delivery); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// NewDeliverWebhooksUseCase claims up to batchSize deliveries at a time for
+// lease, which must outlast the time the sender takes to give up on a webhook
+func NewDeliverWebhooksUseCase(unitOfWork UnitOfWork, sender WebhookSender, policy WebhookRetryPolicy, batchSize int, lease time.Duration) *DeliverWebhooksUseCase {
+	return &DeliverWebhooksUseCase{
+		unitOfWork: unitOfWork,
+		sender:     sender,
+		policy:     policy,
+		batchSize:  batchSize,
+		lease:      lease,
+	}
+}
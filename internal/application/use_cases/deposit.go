@@ -0,0 +1,24 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type DepositUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *DepositUseCase) Execute(ctx context.Context, accountID string, amount banking.Money) (*banking.Account, error) {
+	return updateAccount(ctx, uc.unitOfWork, accountID, func(account *banking.Account) error {
+		if amount.Currency != account.Currency {
+			return banking.ErrCurrencyMismatch
+		}
+		return banking.Deposit(account, amount.Amount)
+	})
+}
+
+func NewDepositUseCase(unitOfWork UnitOfWork) *DepositUseCase {
+	return &DepositUseCase{unitOfWork: unitOfWork}
+}
@@ -0,0 +1,38 @@
+package usecases_test
+
+import (
+	"testing"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepositAndWithdrawUseCases(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	deposit := usecases.NewDepositUseCase(testBackend.unitOfWork)
+	withdraw := usecases.NewWithdrawUseCase(testBackend.unitOfWork)
+	createAccount(t, "acc1", 100)
+
+	account, err := deposit.Execute(t.Context(), "acc1", banking.NewMoney(50, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 150, account.Balance)
+
+	account, err = withdraw.Execute(t.Context(), "acc1", banking.NewMoney(120, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 30, account.Balance)
+
+	_, err = withdraw.Execute(t.Context(), "acc1", banking.NewMoney(100, "EUR"))
+	require.Error(t, err)
+
+	_, err = deposit.Execute(t.Context(), "acc1", banking.NewMoney(50, "USD"))
+	require.ErrorIs(t, err, banking.ErrCurrencyMismatch)
+
+	require.Equal(t, 30, getAccountBalance(t, "acc1"))
+
+	entries, err := testBackend.accounts.FindLedgerEntries(t.Context(), "acc1")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
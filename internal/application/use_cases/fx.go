@@ -0,0 +1,20 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+var ErrRateUnavailable = errors.New("fx rate unavailable")
+
+// FXRateProvider quotes the rate used to convert money between two currencies
+type FXRateProvider interface {
+	Rate(ctx context.Context, base, quote string) (banking.Rate, error)
+}
+
+// ConversionRepository keeps the record of every conversion applied to a transfer
+type ConversionRepository interface {
+	SaveConversion(ctx context.Context, conversion banking.Conversion) error
+}
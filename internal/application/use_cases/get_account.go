@@ -0,0 +1,19 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type GetAccountUseCase struct {
+	accountRepository AccountRepository
+}
+
+func (uc *GetAccountUseCase) Execute(ctx context.Context, id string) (*banking.Account, error) {
+	return uc.accountRepository.Find(ctx, id)
+}
+
+func NewGetAccountUseCase(accountRepository AccountRepository) *GetAccountUseCase {
+	return &GetAccountUseCase{accountRepository: accountRepository}
+}
@@ -0,0 +1,110 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+var ErrNoDailyBalance = errors.New("no daily balance")
+
+// BalanceHistory reads the balances accounts ended their days with, along
+// with the ledger entries in between
+type BalanceHistory interface {
+	LedgerReader
+	// FindOpenedAt returns when the account was first saved, or the zero
+	// time if that was not recorded
+	FindOpenedAt(ctx context.Context, accountID string) (time.Time, error)
+	// FindDailyBalance returns the balance of the account at the end of the
+	// first day on or after day that it was saved, or ErrNoDailyBalance if
+	// it was not saved since
+	FindDailyBalance(ctx context.Context, accountID string, day time.Time) (banking.DailyBalance, error)
+	// FindLedgerEntriesBetween returns the ledger entries of an account written within [from, to)
+	FindLedgerEntriesBetween(ctx context.Context, accountID string, from, to time.Time) ([]banking.LedgerEntry, error)
+}
+
+// AccountHistory rebuilds accounts as they were in the past, which
+// accounts stored as their events allow
+type AccountHistory interface {
+	// FindAt returns the account as it was at the given time, or
+	// banking.ErrAccountNotFound if it was not opened yet
+	FindAt(ctx context.Context, id string, at time.Time) (*banking.Account, error)
+}
+
+type GetBalanceAtUseCase struct {
+	unitOfWork     UnitOfWork
+	accountHistory AccountHistory
+}
+
+// Execute returns the balance the account had at the given time, as the
+// closing balance of a statement ending then. It works backwards from the
+// balance the account ended that day with, or the first day after it was
+// saved, so it reads the entries of a day at most. An account not saved
+// since is worked back from its current balance. The account is not found
+// at times before it was opened. The account and its history are read in
+// one transaction, again if the account changed meanwhile, as
+// GetStatementUseCase does. With an AccountHistory, the account is rebuilt
+// as it was then instead.
+func (uc *GetBalanceAtUseCase) Execute(ctx context.Context, accountID string, at time.Time) (banking.Money, error) {
+	if uc.accountHistory != nil {
+		account, err := uc.accountHistory.FindAt(ctx, accountID, at)
+		if err != nil {
+			return banking.Money{}, err
+		}
+		return account.Money(), nil
+	}
+
+	var balance banking.Money
+	err := retry(ctx, func() error {
+		return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			account, err := repos.Accounts().Find(ctx, accountID)
+			if err != nil {
+				return err
+			}
+
+			history := repos.History()
+			opened, err := history.FindOpenedAt(ctx, accountID)
+			if err != nil {
+				return err
+			}
+			if at.Before(opened) {
+				return banking.ErrAccountNotFound
+			}
+
+			later := account.Balance
+			var since []banking.LedgerEntry
+			daily, err := history.FindDailyBalance(ctx, accountID, banking.StartOfDay(at))
+			switch {
+			case err == nil:
+				later = daily.Balance
+				since, err = history.FindLedgerEntriesBetween(ctx, accountID, at, daily.End())
+			case errors.Is(err, ErrNoDailyBalance):
+				since, err = history.FindLedgerEntriesSince(ctx, accountID, at)
+			}
+			if err != nil {
+				return err
+			}
+			if err := checkUnchanged(ctx, repos.Accounts(), account); err != nil {
+				return err
+			}
+
+			balance = banking.NewMoney(banking.BalanceAt(accountID, later, at, since), account.Currency)
+			return nil
+		})
+	})
+	if err != nil {
+		return banking.Money{}, err
+	}
+	return balance, nil
+}
+
+// NewGetBalanceAtUseCase takes a nil accountHistory when accounts are
+// stored as their state
+func NewGetBalanceAtUseCase(unitOfWork UnitOfWork, accountHistory AccountHistory) *GetBalanceAtUseCase {
+	return &GetBalanceAtUseCase{
+		unitOfWork:     unitOfWork,
+		accountHistory: accountHistory,
+	}
+}
@@ -0,0 +1,60 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+var ErrInvalidDateRange = errors.New("invalid date range")
+
+type LedgerReader interface {
+	FindLedgerEntriesSince(ctx context.Context, accountID string, since time.Time) ([]banking.LedgerEntry, error)
+}
+
+type GetStatementUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+// Execute reads the account and its ledger entries in one transaction, so
+// the balances of the statement match its entries. When the transaction
+// does not lock the account, a change saved while they were read makes it
+// read them again.
+func (uc *GetStatementUseCase) Execute(ctx context.Context, accountID string, from, to time.Time) (banking.Statement, error) {
+	if !from.Before(to) {
+		return banking.Statement{}, ErrInvalidDateRange
+	}
+
+	var statement banking.Statement
+	err := retry(ctx, func() error {
+		return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			account, err := repos.Accounts().Find(ctx, accountID)
+			if err != nil {
+				return err
+			}
+
+			since, err := repos.History().FindLedgerEntriesSince(ctx, accountID, from)
+			if err != nil {
+				return err
+			}
+			if err := checkUnchanged(ctx, repos.Accounts(), account); err != nil {
+				return err
+			}
+
+			statement = banking.NewStatement(account, from, to, since)
+			return nil
+		})
+	})
+	if err != nil {
+		return banking.Statement{}, err
+	}
+	return statement, nil
+}
+
+func NewGetStatementUseCase(unitOfWork UnitOfWork) *GetStatementUseCase {
+	return &GetStatementUseCase{
+		unitOfWork: unitOfWork,
+	}
+}
@@ -0,0 +1,19 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type GetTransferUseCase struct {
+	transferRepository TransferRepository
+}
+
+func (uc *GetTransferUseCase) Execute(ctx context.Context, id string) (*banking.MoneyTransfer, error) {
+	return uc.transferRepository.FindTransfer(ctx, id)
+}
+
+func NewGetTransferUseCase(transferRepository TransferRepository) *GetTransferUseCase {
+	return &GetTransferUseCase{transferRepository: transferRepository}
+}
@@ -0,0 +1,35 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// IdempotencyKey remembers a request that already went through, so a retry
+// carrying the same key is answered with the transfer it created.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	TransferID  string
+	CreatedAt   time.Time
+}
+
+type IdempotencyRepository interface {
+	// FindIdempotencyKey returns nil when the key has never been used
+	FindIdempotencyKey(ctx context.Context, key string) (*IdempotencyKey, error)
+	SaveIdempotencyKey(ctx context.Context, key IdempotencyKey) error
+}
+
+func requestHash(fields ...any) string {
+	sum := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(sum, "%v\x00", field)
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
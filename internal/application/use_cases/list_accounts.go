@@ -0,0 +1,49 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+type AccountLister interface {
+	List(ctx context.Context, after string, limit int) ([]*banking.Account, error)
+}
+
+type ListAccountsUseCase struct {
+	accountLister AccountLister
+}
+
+// Execute returns a page of accounts ordered by ID. The page token is the ID
+// of the last account of the previous page; an empty next token means there
+// are no more pages.
+func (uc *ListAccountsUseCase) Execute(ctx context.Context, pageToken string, pageSize int) ([]*banking.Account, string, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	// Ask for one more account to find out whether there is a next page
+	accounts, err := uc.accountLister.List(ctx, pageToken, pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(accounts) <= pageSize {
+		return accounts, "", nil
+	}
+
+	accounts = accounts[:pageSize]
+	return accounts, accounts[pageSize-1].ID, nil
+}
+
+func NewListAccountsUseCase(accountLister AccountLister) *ListAccountsUseCase {
+	return &ListAccountsUseCase{accountLister: accountLister}
+}
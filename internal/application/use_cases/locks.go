@@ -0,0 +1,35 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	ErrLockLost        = errors.New("lock lost")
+)
+
+// LockManager hands out locks shared by every instance of the service
+type LockManager interface {
+	// Acquire locks all the keys, always in the same order so that two callers
+	// asking for overlapping keys cannot deadlock. It gives up with
+	// ErrLockNotAcquired after a while, leaving none of the keys locked.
+	Acquire(ctx context.Context, keys ...string) (Lock, error)
+}
+
+// Lock is a lease on a set of keys. It expires unless it is renewed, so a
+// crashed holder cannot keep the keys forever.
+type Lock interface {
+	// Token is a fencing token, greater than the token of any lock acquired
+	// before. AccountRepository.SaveFenced rejects the writes of a holder that lost its lease.
+	Token() int64
+	// Refresh renews the lease, failing with ErrLockLost if it already expired
+	Refresh(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// AccountLockKey is the lock key guarding an account
+func AccountLockKey(accountID string) string {
+	return "account:" + accountID
+}
@@ -0,0 +1,182 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/db"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+func TestRedisLockManager(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	locks := db.NewRedisLockManager(testRedis, time.Second, 50*time.Millisecond)
+
+	first, err := locks.Acquire(t.Context(), "c", "b")
+	require.NoError(t, err)
+
+	// Overlapping keys wait, then give up without keeping the free ones
+	_, err = locks.Acquire(t.Context(), "c", "a")
+	require.ErrorIs(t, err, usecases.ErrLockNotAcquired)
+
+	other, err := locks.Acquire(t.Context(), "a")
+	require.NoError(t, err)
+	require.Greater(t, other.Token(), first.Token())
+	require.NoError(t, other.Release(t.Context()))
+
+	require.NoError(t, first.Refresh(t.Context()))
+	require.NoError(t, first.Release(t.Context()))
+
+	second, err := locks.Acquire(t.Context(), "b", "c")
+	require.NoError(t, err)
+	require.Greater(t, second.Token(), first.Token())
+	require.ErrorIs(t, first.Refresh(t.Context()), usecases.ErrLockLost)
+	require.NoError(t, second.Release(t.Context()))
+}
+
+func TestRedisLockManager_RenewsLeases(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	locks := db.NewRedisLockManager(testRedis, 300*time.Millisecond, 0)
+
+	lock, err := locks.Acquire(t.Context(), "a")
+	require.NoError(t, err)
+
+	// The lease is renewed in the background while the lock is held
+	time.Sleep(time.Second)
+	_, err = locks.Acquire(t.Context(), "a")
+	require.ErrorIs(t, err, usecases.ErrLockNotAcquired)
+	require.NoError(t, lock.Refresh(t.Context()))
+	require.NoError(t, lock.Release(t.Context()))
+
+	_, err = locks.Acquire(t.Context(), "a")
+	require.NoError(t, err)
+}
+
+// flakyScripts fails the next Lua scripts sent to Redis, like a network blip
+type flakyScripts struct {
+	failures *atomic.Int32
+}
+
+func (h flakyScripts) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h flakyScripts) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if name := cmd.Name(); (name == "evalsha" || name == "eval") && h.failures.Add(-1) >= 0 {
+			err := errors.New("i/o timeout")
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h flakyScripts) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisLockManager_RenewsLeasesAfterErrors(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	var failures atomic.Int32
+	rdb := redis.NewClient(testRedis.Options())
+	defer rdb.Close()
+	rdb.AddHook(flakyScripts{&failures})
+	locks := db.NewRedisLockManager(rdb, 300*time.Millisecond, 0)
+
+	lock, err := locks.Acquire(t.Context(), "a")
+	require.NoError(t, err)
+
+	// The first renewal fails, and the next one still keeps the lease alive
+	failures.Store(1)
+	time.Sleep(time.Second)
+	_, err = locks.Acquire(t.Context(), "a")
+	require.ErrorIs(t, err, usecases.ErrLockNotAcquired)
+	require.NoError(t, lock.Release(t.Context()))
+}
+
+func TestTransferMoneyUseCase_DistributedLocks(t *testing.T) {
+	requireDatabase(t)
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 1000)
+	createAccount(t, "acc2", 1000)
+
+	// Two instances of the service sharing the same database and Redis
+	newReplica := func() *usecases.TransferMoneyUseCase {
+		return usecases.NewTransferMoneyUseCase(
+			newUnitOfWork(),
+			db.NewFXRepository(testDB, testDialect),
+			db.NewRedisLockManager(testRedis, testConfig.Locks.TTL, testConfig.Locks.Wait),
+		)
+	}
+	replicas := []*usecases.TransferMoneyUseCase{newReplica(), newReplica()}
+
+	numTransfers := 10
+	errChan := make(chan error, numTransfers*2)
+	for i := 0; i < numTransfers; i++ {
+		go func() {
+			_, err := replicas[0].Execute(t.Context(), "acc1", "acc2", banking.NewMoney(100, "EUR"))
+			errChan <- err
+		}()
+		go func() {
+			_, err := replicas[1].Execute(t.Context(), "acc2", "acc1", banking.NewMoney(100, "EUR"))
+			errChan <- err
+		}()
+	}
+
+	for i := 0; i < numTransfers*2; i++ {
+		require.NoError(t, <-errChan)
+	}
+	require.Equal(t, 1000, getAccountBalance(t, "acc1"))
+	require.Equal(t, 1000, getAccountBalance(t, "acc2"))
+}
+
+// staleLocks hands out locks with a fencing token that was superseded, as
+// if their lease had expired and been taken over by another instance
+type staleLocks struct{ token int64 }
+
+func (l staleLocks) Acquire(ctx context.Context, keys ...string) (usecases.Lock, error) {
+	return l, nil
+}
+
+func (l staleLocks) Token() int64                      { return l.token }
+func (l staleLocks) Refresh(ctx context.Context) error { return nil }
+func (l staleLocks) Release(ctx context.Context) error { return nil }
+
+func TestTransferMoneyUseCase_RejectsStaleFencingTokens(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 1000)
+	createAccount(t, "acc2", 1000)
+	account, err := testBackend.accounts.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	require.NoError(t, testBackend.accounts.SaveFenced(t.Context(), account, 5))
+
+	useCase := usecases.NewTransferMoneyUseCase(testBackend.unitOfWork, testBackend.fxRates, staleLocks{token: 4})
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(100, "EUR"))
+	require.ErrorIs(t, err, usecases.ErrLockLost)
+	require.Equal(t, 1000, getAccountBalance(t, "acc1"))
+	require.Equal(t, 1000, getAccountBalance(t, "acc2"))
+
+	useCase = usecases.NewTransferMoneyUseCase(testBackend.unitOfWork, testBackend.fxRates, staleLocks{token: 6})
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(100, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 900, getAccountBalance(t, "acc1"))
+}
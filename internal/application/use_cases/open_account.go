@@ -0,0 +1,30 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type OpenAccountUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *OpenAccountUseCase) Execute(ctx context.Context, owner, currency, actor string) (*banking.Account, error) {
+	account, err := banking.OpenAccount(owner, currency, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		return repos.Accounts().Save(ctx, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+func NewOpenAccountUseCase(unitOfWork UnitOfWork) *OpenAccountUseCase {
+	return &OpenAccountUseCase{unitOfWork: unitOfWork}
+}
@@ -0,0 +1,165 @@
+package usecases_test
+
+import (
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAccountsUseCase_Paginates(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	for _, id := range []string{"acc1", "acc2", "acc3", "acc4", "acc5"} {
+		createAccount(t, id, 100)
+	}
+	listAccounts := usecases.NewListAccountsUseCase(testBackend.accounts)
+
+	var ids []string
+	pageToken, pages := "", 0
+	for {
+		accounts, next, err := listAccounts.Execute(t.Context(), pageToken, 2)
+		require.NoError(t, err)
+		for _, account := range accounts {
+			ids = append(ids, account.ID)
+		}
+
+		pages++
+		if next == "" {
+			break
+		}
+		pageToken = next
+	}
+
+	require.Equal(t, []string{"acc1", "acc2", "acc3", "acc4", "acc5"}, ids)
+	require.Equal(t, 3, pages)
+}
+
+func TestGetStatementUseCase_Execute(t *testing.T) {
+	transferMoney, cleanup := setupTest(t)
+	defer cleanup()
+
+	repo := testBackend.accounts
+	getAccount := usecases.NewGetAccountUseCase(repo)
+	getStatement := usecases.NewGetStatementUseCase(testBackend.unitOfWork)
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+	from := time.Now().Add(-time.Minute)
+
+	_, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+	_, err = transferMoney.Execute(t.Context(), "acc2", "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+
+	account, err := getAccount.Execute(t.Context(), "acc1")
+	require.NoError(t, err)
+	require.Equal(t, 80, account.Balance)
+
+	statement, err := getStatement.Execute(t.Context(), "acc1", from, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 100, statement.OpeningBalance)
+	require.Equal(t, 80, statement.ClosingBalance)
+	require.Len(t, statement.Entries, 2)
+
+	_, err = getStatement.Execute(t.Context(), "acc1", time.Now(), from)
+	require.ErrorIs(t, err, usecases.ErrInvalidDateRange)
+
+	_, err = getAccount.Execute(t.Context(), "unknown")
+	require.ErrorIs(t, err, banking.ErrAccountNotFound)
+}
+
+func TestGetBalanceAtUseCase_Execute(t *testing.T) {
+	transferMoney, cleanup := setupTest(t)
+	defer cleanup()
+
+	getBalanceAt := usecases.NewGetBalanceAtUseCase(testBackend.unitOfWork, testBackend.accountHistory)
+
+	// wait keeps the times read apart from those of the entries around them
+	wait := func() time.Time {
+		time.Sleep(2 * time.Millisecond)
+		at := time.Now()
+		time.Sleep(2 * time.Millisecond)
+		return at
+	}
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+	before := wait()
+
+	_, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+	between := wait()
+	_, err = transferMoney.Execute(t.Context(), "acc2", "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+	after := wait()
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"before any transfer", before, 100},
+		{"between the transfers", between, 70},
+		{"after the transfers", after, 80},
+		{"tomorrow", after.AddDate(0, 0, 1), 80},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, err := getBalanceAt.Execute(t.Context(), "acc1", tt.at)
+			require.NoError(t, err)
+			require.Equal(t, banking.NewMoney(tt.want, "EUR"), balance)
+		})
+	}
+
+	_, err = getBalanceAt.Execute(t.Context(), "acc1", before.AddDate(0, 0, -1))
+	require.ErrorIs(t, err, banking.ErrAccountNotFound, "the account was not opened yet")
+
+	_, err = getBalanceAt.Execute(t.Context(), "unknown", after)
+	require.ErrorIs(t, err, banking.ErrAccountNotFound)
+}
+
+func TestGetBalanceAtUseCase_ConcurrentTransfers(t *testing.T) {
+	for _, locking := range lockingModes {
+		t.Run(string(locking), func(t *testing.T) {
+			transferMoney, cleanup := setupTestWithLocking(t, locking)
+			defer cleanup()
+			getBalanceAt := usecases.NewGetBalanceAtUseCase(testBackend.unitOfWork, testBackend.accountHistory)
+
+			createAccount(t, "acc1", 1000)
+			createAccount(t, "acc2", 0)
+			time.Sleep(2 * time.Millisecond)
+			at := time.Now()
+			time.Sleep(2 * time.Millisecond)
+
+			// Transfers keep changing the balance and the entries after at
+			// while the balance at it is read
+			const transfers = 100
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for range transfers {
+					if _, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(1, "EUR")); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}()
+			defer func() { <-done }()
+
+			for reading := true; reading; {
+				select {
+				case <-done:
+					reading = false
+				default:
+				}
+				balance, err := getBalanceAt.Execute(t.Context(), "acc1", at)
+				require.NoError(t, err)
+				require.Equal(t, banking.NewMoney(1000, "EUR"), balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package usecases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// ErrRelayLeaseLost is reported by a relay that was taken over by another
+// one while it published, which publishes the same events again
+var ErrRelayLeaseLost = errors.New("relay lease lost")
+
+// OutboxMessage is a domain event saved in the same transaction as the
+// change that raised it, until it is published
+type OutboxMessage struct {
+	// ID orders the messages in the outbox
+	ID          int64
+	EventID     string
+	EventType   string
+	OrderingKey string
+	// Payload is the event as JSON
+	Payload    []byte
+	OccurredAt time.Time
+}
+
+// NewOutboxMessage serializes event for the outbox. Its ID is set when it is saved.
+func NewOutboxMessage(event banking.Event) (OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return OutboxMessage{}, err
+	}
+
+	metadata := event.Metadata()
+	return OutboxMessage{
+		EventID:     metadata.EventID,
+		EventType:   event.EventType(),
+		OrderingKey: event.OrderingKey(),
+		Payload:     payload,
+		OccurredAt:  metadata.OccurredAt,
+	}, nil
+}
+
+// Outbox holds the events saved by AccountRepository and TransferRepository
+// until they are published
+type Outbox interface {
+	// AcquireRelayLease makes holder the only relay of the outbox until
+	// expiresAt, and reports whether it could: the lease must be free, over
+	// by now, or held by holder already
+	AcquireRelayLease(ctx context.Context, holder string, now, expiresAt time.Time) (bool, error)
+	// PendingMessages returns up to limit messages not published yet, in ID order
+	PendingMessages(ctx context.Context, limit int) ([]OutboxMessage, error)
+	MarkPublished(ctx context.Context, ids ...int64) error
+}
+
+// EventPublisher delivers events to other services
+type EventPublisher interface {
+	Publish(ctx context.Context, message OutboxMessage) error
+}
+
+// EventSubscriber handles events within the transaction of the relay, so
+// what it writes commits along with the events being marked published
+type EventSubscriber interface {
+	HandleEvent(ctx context.Context, repos Repositories, message OutboxMessage) error
+}
+
+type RelayEventsUseCase struct {
+	unitOfWork  UnitOfWork
+	publisher   EventPublisher
+	batchSize   int
+	lease       time.Duration
+	subscribers []EventSubscriber
+	// holder tells this relay apart from the others holding the lease
+	holder string
+}
+
+// Execute publishes a batch of the oldest events in the outbox, in order, and
+// returns how many it published. An event is only marked published once the
+// publisher took it, so delivery is at least once: a relay failing in
+// between publishes it again.
+//
+// Only the relay holding the lease of the outbox publishes, so relays
+// running side by side cannot reorder the events; the others publish
+// nothing until it expires. No transaction stays open while the events are
+// published: the batch is claimed in one, and marked published in another.
+// The subscribers handle the published events in the latter. When one of
+// them fails, the batch is published again later.
+func (uc *RelayEventsUseCase) Execute(ctx context.Context) (int, error) {
+	messages, err := uc.claim(ctx)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	// Stop at the first failure, so no event overtakes the one that failed
+	published := messages
+	var publishErr error
+	if uc.publisher != nil {
+		for i, message := range messages {
+			if publishErr = uc.publisher.Publish(ctx, message); publishErr != nil {
+				published = messages[:i]
+				break
+			}
+		}
+	}
+	if len(published) == 0 {
+		return 0, publishErr
+	}
+
+	if err := uc.markPublished(ctx, published); err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
+
+// claim takes the lease of the outbox, and returns the batch to publish
+// unless another relay holds it
+func (uc *RelayEventsUseCase) claim(ctx context.Context) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	err := uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		messages = nil
+		now := time.Now().UTC()
+		acquired, err := repos.Outbox().AcquireRelayLease(ctx, uc.holder, now, now.Add(uc.lease))
+		if err != nil || !acquired {
+			return err
+		}
+
+		messages, err = repos.Outbox().PendingMessages(ctx, uc.batchSize)
+		return err
+	})
+	return messages, err
+}
+
+// markPublished hands the published events to the subscribers, and marks them
+// published, as long as this relay still holds the lease
+func (uc *RelayEventsUseCase) markPublished(ctx context.Context, published []OutboxMessage) error {
+	return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		now := time.Now().UTC()
+		acquired, err := repos.Outbox().AcquireRelayLease(ctx, uc.holder, now, now.Add(uc.lease))
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return ErrRelayLeaseLost
+		}
+
+		ids := make([]int64, len(published))
+		for i, message := range published {
+			for _, subscriber := range uc.subscribers {
+				if err := subscriber.HandleEvent(ctx, repos, message); err != nil {
+					return err
+				}
+			}
+			ids[i] = message.ID
+		}
+		return repos.Outbox().MarkPublished(ctx, ids...)
+	})
+}
+
+// NewRelayEventsUseCase relays the events to publisher, unless it is nil, and
+// to subscribers. Its lease on the outbox must outlast the time it takes to
+// publish a batch of batchSize events.
+func NewRelayEventsUseCase(unitOfWork UnitOfWork, publisher EventPublisher, batchSize int, lease time.Duration, subscribers ...EventSubscriber) *RelayEventsUseCase {
+	return &RelayEventsUseCase{
+		unitOfWork:  unitOfWork,
+		publisher:   publisher,
+		batchSize:   batchSize,
+		lease:       lease,
+		subscribers: subscribers,
+		holder:      newID(),
+	}
+}
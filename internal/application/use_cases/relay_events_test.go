@@ -0,0 +1,130 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayEventsUseCase(t *testing.T) {
+	transferMoney, cleanup := setupTest(t)
+	defer cleanup()
+
+	var published []usecases.OutboxMessage
+	failing := false
+	publisher := services.NewInProcessPublisher()
+	publisher.Subscribe(func(ctx context.Context, message usecases.OutboxMessage) error {
+		if failing {
+			return errors.New("broker unavailable")
+		}
+		published = append(published, message)
+		return nil
+	})
+	relay := usecases.NewRelayEventsUseCase(testBackend.unitOfWork, publisher, 10, time.Minute)
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 0)
+	_, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	failing = true
+	count, err := relay.Execute(t.Context())
+	require.Error(t, err)
+	require.Zero(t, count)
+
+	failing = false
+	count, err = relay.Execute(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	types := make([]string, len(published))
+	for i, message := range published {
+		types[i] = message.EventType
+	}
+	require.Equal(t, []string{banking.EventAccountDebited, banking.EventAccountCredited, banking.EventMoneyTransferred}, types)
+	require.Equal(t, []string{"acc1", "acc2", "acc1"}, []string{published[0].OrderingKey, published[1].OrderingKey, published[2].OrderingKey})
+	require.Less(t, published[0].ID, published[1].ID)
+
+	count, err = relay.Execute(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestRelayEventsUseCase_Lease(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	deposit := usecases.NewDepositUseCase(testBackend.unitOfWork)
+	_, err := deposit.Execute(t.Context(), "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+
+	var published []usecases.OutboxMessage
+	publisher := services.NewInProcessPublisher()
+	publisher.Subscribe(func(ctx context.Context, message usecases.OutboxMessage) error {
+		published = append(published, message)
+		return nil
+	})
+	relay := usecases.NewRelayEventsUseCase(testBackend.unitOfWork, publisher, 10, time.Minute)
+
+	// A relay failing to publish keeps the others out until its lease is over
+	const lease = 100 * time.Millisecond
+	broken := services.NewInProcessPublisher()
+	broken.Subscribe(func(ctx context.Context, message usecases.OutboxMessage) error {
+		return errors.New("broker unavailable")
+	})
+	_, err = usecases.NewRelayEventsUseCase(testBackend.unitOfWork, broken, 10, lease).Execute(t.Context())
+	require.Error(t, err)
+
+	count, err := relay.Execute(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	time.Sleep(2 * lease)
+	count, err = relay.Execute(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Len(t, published, 1)
+}
+
+func TestRelayEventsUseCase_LeaseLost(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	_, err := usecases.NewDepositUseCase(testBackend.unitOfWork).Execute(t.Context(), "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+
+	var published []usecases.OutboxMessage
+	publisher := services.NewInProcessPublisher()
+	publisher.Subscribe(func(ctx context.Context, message usecases.OutboxMessage) error {
+		published = append(published, message)
+		return nil
+	})
+	other := usecases.NewRelayEventsUseCase(testBackend.unitOfWork, publisher, 10, time.Minute)
+
+	// No transaction is open while a relay publishes, so another one can
+	// take over once it outlived its lease
+	const lease = 100 * time.Millisecond
+	slow := services.NewInProcessPublisher()
+	slow.Subscribe(func(ctx context.Context, message usecases.OutboxMessage) error {
+		time.Sleep(2 * lease)
+		count, err := other.Execute(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, count)
+		return nil
+	})
+	_, err = usecases.NewRelayEventsUseCase(testBackend.unitOfWork, slow, 10, lease).Execute(t.Context())
+	require.ErrorIs(t, err, usecases.ErrRelayLeaseLost)
+	require.Len(t, published, 1)
+
+	count, err := other.Execute(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// ErrTransactionConflict is reported by repositories when the database
+// aborted a transaction because of a deadlock or a lock wait timeout. The
+// whole transaction can safely run again.
+var ErrTransactionConflict = errors.New("transaction conflict")
+
+// ErrConcurrentModification is reported by repositories when a record was
+// changed by someone else since it was read. Reading it again and
+// repeating the change can succeed.
+var ErrConcurrentModification = errors.New("concurrent modification")
+
+const (
+	maxAttempts  = 5
+	retryBackoff = 10 * time.Millisecond
+)
+
+// retry runs fn again while it fails with ErrTransactionConflict or
+// ErrConcurrentModification, backing off exponentially with jitter so the
+// conflicting transactions do not collide again. It stops waiting as soon
+// as ctx is done.
+func retry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if err = fn(); !errors.Is(err, ErrTransactionConflict) && !errors.Is(err, ErrConcurrentModification) {
+			return err
+		}
+
+		if attempt < maxAttempts-1 {
+			backoff := retryBackoff << attempt
+			timer := time.NewTimer(backoff/2 + rand.N(backoff/2))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return err
+}
+
+// checkUnchanged fails with ErrConcurrentModification if the account was
+// saved since it was read. A transaction that does not lock the account
+// checks it after reading its history, so that retry reads both again
+// rather than mixing a balance with the entries of another one.
+func checkUnchanged(ctx context.Context, accounts AccountRepository, account *banking.Account) error {
+	found, err := accounts.Find(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	if found.Version != account.Version {
+		return ErrConcurrentModification
+	}
+	return nil
+}
@@ -1,65 +1,288 @@
 package usecases
 
 import (
-	"database/sql"
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/ppicom/newtonian/internal/domain/banking"
 )
 
+// AccountRepository finds and saves accounts. Within a transaction, Find
+// locks the account until the transaction ends. Save adds the pending events
+// of the account to the outbox, as SaveTransfer does for a transfer.
 type AccountRepository interface {
-	Find(tx *sql.Tx, id string) (*banking.Account, error)
-	Save(tx *sql.Tx, account *banking.Account) error
-	BeginTx() (*sql.Tx, error)
-	CommitTx(tx *sql.Tx) error
-	RollbackTx(tx *sql.Tx) error
+	Find(ctx context.Context, id string) (*banking.Account, error)
+	Save(ctx context.Context, account *banking.Account) error
+	// SaveFenced saves the account as Save does, for the holder of a lock
+	// with the given fencing token. It fails with ErrLockLost if the account
+	// was saved since under a lock with a greater token, whose holder took
+	// the account over once this lock expired.
+	SaveFenced(ctx context.Context, account *banking.Account, token int64) error
+}
+
+type TransferRepository interface {
+	FindTransfer(ctx context.Context, id string) (*banking.MoneyTransfer, error)
+	SaveTransfer(ctx context.Context, transfer *banking.MoneyTransfer) error
 }
 
 type TransferMoneyUseCase struct {
-	accountRepository AccountRepository
-	mu                sync.Mutex
+	unitOfWork     UnitOfWork
+	fxRateProvider FXRateProvider
+	lockManager    LockManager
+}
+
+func (uc *TransferMoneyUseCase) Execute(ctx context.Context, from, to string, amount banking.Money) (*banking.MoneyTransfer, error) {
+	return uc.ExecuteIdempotent(ctx, "", from, to, amount)
+}
+
+// ExecuteIdempotent runs the transfer at most once per idempotency key. A retry
+// with the same key and payload returns the original transfer without moving
+// the money again, while one with a different payload fails with
+// ErrIdempotencyKeyReused. An empty key disables the check. A transfer from
+// an account to itself is refused with banking.ErrSameAccount.
+//
+// Transfers are isolated by the database: each one locks the rows of its two
+// accounts, so transfers between disjoint accounts run in parallel. Deadlocks
+// and lock wait timeouts are retried from scratch. With a lock manager, both
+// accounts are also locked across instances for the whole transfer.
+//
+// A transfer that was attempted and did not go through, including one still
+// running when ctx is canceled or its deadline passes, is rolled back and
+// recorded as failed. Requests refused before moving any money, such as a
+// reused idempotency key or accounts locked by someone else, are not.
+func (uc *TransferMoneyUseCase) ExecuteIdempotent(ctx context.Context, idempotencyKey, from, to string, amount banking.Money) (*banking.MoneyTransfer, error) {
+	if from == to {
+		return nil, banking.ErrSameAccount
+	}
+
+	transfer := banking.NewMoneyTransfer(from, to, amount)
+	var attempted bool
+	if err := uc.executeLocked(ctx, idempotencyKey, transfer, &attempted); err != nil {
+		if attempted && transfer.Status == banking.TransferPending {
+			if recordErr := uc.recordFailure(ctx, transfer, err); recordErr != nil {
+				return transfer, errors.Join(err, fmt.Errorf("recording the failed transfer: %w", recordErr))
+			}
+		}
+		return transfer, err
+	}
+	return transfer, nil
 }
 
-func (uc *TransferMoneyUseCase) Execute(from, to string, amount int) error {
-	// Lock the use case to guarantee concurrent transfers are serialized and happen in order
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+// executeLocked holds the distributed locks of both accounts, when there is a
+// lock manager, across every attempt of the transfer. attempted is set once
+// an attempt gets to move the money.
+func (uc *TransferMoneyUseCase) executeLocked(ctx context.Context, idempotencyKey string, transfer *banking.MoneyTransfer, attempted *bool) error {
+	var lock Lock
+	if uc.lockManager != nil {
+		var err error
+		lock, err = uc.lockManager.Acquire(ctx, AccountLockKey(transfer.From), AccountLockKey(transfer.To))
+		if err != nil {
+			return err
+		}
+		// Release the locks even when ctx is canceled, rather than let them expire
+		defer lock.Release(context.WithoutCancel(ctx))
+	}
+
+	initial := *transfer
+	return retry(ctx, func() error {
+		*transfer = initial
+		err := uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+			return uc.execute(ctx, repos, idempotencyKey, transfer, lock, attempted)
+		})
+		// A transfer completed in a transaction that did not commit is still pending
+		if err != nil && transfer.Status == banking.TransferCompleted && transfer.ID == initial.ID {
+			transfer.Status = banking.TransferPending
+		}
+		return err
+	})
+}
+
+func (uc *TransferMoneyUseCase) execute(ctx context.Context, repos Repositories, idempotencyKey string, transfer *banking.MoneyTransfer, lock Lock, attempted *bool) error {
+	if idempotencyKey != "" {
+		hash := requestHash(transfer.From, transfer.To, transfer.Amount.Amount, transfer.Amount.Currency)
+		key, err := repos.IdempotencyKeys().FindIdempotencyKey(ctx, idempotencyKey)
+		if err != nil {
+			return err
+		}
+
+		if key != nil {
+			if key.RequestHash != hash {
+				return ErrIdempotencyKeyReused
+			}
+			return uc.replay(ctx, repos, key, transfer)
+		}
+
+		key = &IdempotencyKey{
+			Key:         idempotencyKey,
+			RequestHash: hash,
+			TransferID:  transfer.ID,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if err := repos.IdempotencyKeys().SaveIdempotencyKey(ctx, *key); err != nil {
+			return err
+		}
+	}
 
-	tx, err := uc.accountRepository.BeginTx()
+	*attempted = true
+	fromAccount, toAccount, err := uc.lockAccounts(ctx, repos.Accounts(), transfer.From, transfer.To)
 	if err != nil {
 		return err
 	}
 
-	fromAccount, err := uc.accountRepository.Find(tx, from)
+	if transfer.Amount.Currency != fromAccount.Currency {
+		return banking.ErrCurrencyMismatch
+	}
+
+	if err := uc.transfer(ctx, repos, fromAccount, toAccount, transfer); err != nil {
+		return err
+	}
+
+	if err := uc.saveAccount(ctx, repos.Accounts(), fromAccount, lock); err != nil {
+		return err
+	}
+
+	if err := uc.saveAccount(ctx, repos.Accounts(), toAccount, lock); err != nil {
+		return err
+	}
+
+	if err := transfer.Complete(); err != nil {
+		return err
+	}
+
+	if err := repos.Transfers().SaveTransfer(ctx, transfer); err != nil {
+		return err
+	}
+
+	// Make sure no other instance can have taken over the accounts meanwhile
+	if lock != nil {
+		return lock.Refresh(ctx)
+	}
+	return nil
+}
+
+// lockAccounts loads both accounts, always locking the lower ID first so that
+// opposite transfers between the same accounts cannot deadlock each other
+func (uc *TransferMoneyUseCase) lockAccounts(ctx context.Context, accounts AccountRepository, from, to string) (*banking.Account, *banking.Account, error) {
+	first, second := from, to
+	if first > second {
+		first, second = second, first
+	}
+
+	firstAccount, err := accounts.Find(ctx, first)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secondAccount, err := accounts.Find(ctx, second)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if first == from {
+		return firstAccount, secondAccount, nil
+	}
+	return secondAccount, firstAccount, nil
+}
+
+// saveAccount fences the save with the token of the lock, when there is one
+func (uc *TransferMoneyUseCase) saveAccount(ctx context.Context, accounts AccountRepository, account *banking.Account, lock Lock) error {
+	if lock != nil {
+		return accounts.SaveFenced(ctx, account, lock.Token())
+	}
+	return accounts.Save(ctx, account)
+}
+
+// replay answers a retried request with the transfer created the first time
+func (uc *TransferMoneyUseCase) replay(ctx context.Context, repos Repositories, key *IdempotencyKey, transfer *banking.MoneyTransfer) error {
+	original, err := repos.Transfers().FindTransfer(ctx, key.TransferID)
 	if err != nil {
-		uc.accountRepository.RollbackTx(tx)
 		return err
 	}
 
-	toAccount, err := uc.accountRepository.Find(tx, to)
+	*transfer = *original
+	return nil
+}
+
+// transfer converts the amount first when the accounts are held in different currencies
+func (uc *TransferMoneyUseCase) transfer(ctx context.Context, repos Repositories, from, to *banking.Account, transfer *banking.MoneyTransfer) error {
+	if from.Currency == to.Currency {
+		return banking.Transfer(from, to, transfer.Amount.Amount)
+	}
+
+	// FX transfers are disabled when there is no rate provider
+	if uc.fxRateProvider == nil {
+		return banking.ErrCurrencyMismatch
+	}
+
+	rate, err := uc.fxRateProvider.Rate(ctx, from.Currency, to.Currency)
 	if err != nil {
-		uc.accountRepository.RollbackTx(tx)
 		return err
 	}
 
-	if err := banking.Transfer(fromAccount, toAccount, amount); err != nil {
-		uc.accountRepository.RollbackTx(tx)
+	conversion, err := banking.Convert(transfer.Amount, rate)
+	if err != nil {
 		return err
 	}
 
-	if err := uc.accountRepository.Save(tx, fromAccount); err != nil {
-		uc.accountRepository.RollbackTx(tx)
+	if err := banking.TransferWithConversion(from, to, conversion); err != nil {
 		return err
 	}
 
-	if err := uc.accountRepository.Save(tx, toAccount); err != nil {
-		uc.accountRepository.RollbackTx(tx)
+	transfer.ConversionID = conversion.ID
+	return repos.Conversions().SaveConversion(ctx, conversion)
+}
+
+// failureReasons are the stable codes recorded on failed transfers, the
+// same the API reports for their errors. Anything else is "internal", so
+// driver and network errors are never shown to clients.
+var failureReasons = []struct {
+	err    error
+	reason string
+}{
+	{banking.ErrInsufficientFunds, "insufficient_funds"},
+	{banking.ErrCurrencyMismatch, "currency_mismatch"},
+	{banking.ErrInvalidSpread, "invalid_spread"},
+	{banking.ErrAccountFrozen, "account_frozen"},
+	{banking.ErrAccountClosed, "account_closed"},
+	{banking.ErrAccountNotFound, "account_not_found"},
+	{ErrRateUnavailable, "rate_unavailable"},
+	{ErrTransactionConflict, "transaction_conflict"},
+	{ErrConcurrentModification, "concurrent_modification"},
+	{ErrLockLost, "lock_lost"},
+	{context.Canceled, "canceled"},
+	{context.DeadlineExceeded, "deadline_exceeded"},
+}
+
+// failureReason is the code recorded on a transfer that failed with err
+func failureReason(err error) string {
+	for _, r := range failureReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+	return "internal"
+}
+
+// recordFailure keeps a trace of transfers that did not go through. The
+// transfer's own transaction is already rolled back, so it gets a new one,
+// which still runs when the transfer failed because ctx was canceled.
+func (uc *TransferMoneyUseCase) recordFailure(ctx context.Context, transfer *banking.MoneyTransfer, cause error) error {
+	if err := transfer.Fail(failureReason(cause)); err != nil {
 		return err
 	}
 
-	return uc.accountRepository.CommitTx(tx)
+	ctx = context.WithoutCancel(ctx)
+	return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		return repos.Transfers().SaveTransfer(ctx, transfer)
+	})
 }
 
-func NewTransferMoneyUseCase(accountRepository AccountRepository) *TransferMoneyUseCase {
-	return &TransferMoneyUseCase{accountRepository: accountRepository}
+func NewTransferMoneyUseCase(unitOfWork UnitOfWork, fxRateProvider FXRateProvider, lockManager LockManager) *TransferMoneyUseCase {
+	return &TransferMoneyUseCase{
+		unitOfWork:     unitOfWork,
+		fxRateProvider: fxRateProvider,
+		lockManager:    lockManager,
+	}
 }
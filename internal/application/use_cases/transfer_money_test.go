@@ -3,12 +3,20 @@ package usecases_test
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"math/big"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
 	"github.com/ppicom/newtonian/internal/infrastructure/db"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
@@ -17,19 +25,80 @@ import (
 )
 
 var (
-	testDB    *sql.DB
-	testRedis *redis.Client
+	testConfig  config.Config
+	testDB      *sql.DB // nil unless the tests run on a SQL database
+	testDialect db.Dialect
+	testRedis   *redis.Client
+	testBackend *backend
 )
 
+// testDialects are the drivers the tests can run on a SQL database, and their dialects
+var testDialects = map[string]db.Dialect{
+	config.DriverMySQL:    db.MySQL,
+	config.DriverPostgres: db.Postgres,
+}
+
+// testSQLDrivers are the database/sql drivers of each dialect
+var testSQLDrivers = map[db.Dialect]string{
+	db.MySQL:    "mysql",
+	db.Postgres: "pgx",
+}
+
+// testTables are emptied before each test on a SQL database
+var testTables = []string{
+	"accounts",
+	"ledger_entries",
+	"fx_rates",
+	"fx_conversions",
+	"idempotency_keys",
+	"transfers",
+	"account_status_changes",
+	"outbox",
+	"webhooks",
+	"webhook_deliveries",
+	"account_streams",
+	"account_events",
+	"account_snapshots",
+	"daily_balances",
+}
+
+// testConfigFile is used unless BANKING_CONFIG points somewhere else
+const testConfigFile = "../../../config/test.yaml"
+
 func TestMain(m *testing.M) {
-	// Setup test infrastructure
+	// Setup test infrastructure from the same configuration the server uses
+	var args []string
+	if os.Getenv(config.EnvPrefix+"CONFIG") == "" {
+		args = []string{"-config", testConfigFile}
+	}
+
 	var err error
-	testDB, err = sql.Open("mysql", "test:testpass@tcp(localhost:3306)/banking_test")
+	testConfig, err = config.Load(args)
+	if err != nil {
+		log.Fatalf("Failed to load test configuration: %v", err)
+	}
+
+	if dialect, ok := testDialects[testConfig.Database.Driver]; ok {
+		setupDatabase(dialect)
+	}
+
+	code := m.Run()
+
+	if testDB != nil {
+		teardownDatabase()
+	}
+	os.Exit(code)
+}
+
+func setupDatabase(dialect db.Dialect) {
+	var err error
+	testDialect = dialect
+	testDB, err = sql.Open(testSQLDrivers[dialect], testConfig.Database.DSN)
 	if err != nil {
 		log.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	// Wait for MySQL to be ready
+	// Wait for the database to be ready
 	for i := 0; i < 30; i++ {
 		err = testDB.Ping()
 		if err == nil {
@@ -38,22 +107,21 @@ func TestMain(m *testing.M) {
 		time.Sleep(1 * time.Second)
 	}
 	if err != nil {
-		log.Fatalf("MySQL not ready after 30 seconds: %v", err)
+		log.Fatalf("Database not ready after 30 seconds: %v", err)
 	}
 
-	// Create test table
-	_, err = testDB.Exec(`
-		CREATE TABLE IF NOT EXISTS accounts (
-			id VARCHAR(255) PRIMARY KEY,
-			balance INT NOT NULL
-		)
-	`)
+	migrator, err := db.NewMigrator(testDB, dialect)
+	if err == nil {
+		_, err = migrator.Up()
+	}
 	if err != nil {
-		log.Fatalf("Failed to create test table: %v", err)
+		log.Fatalf("Failed to migrate test database: %v", err)
 	}
 
 	testRedis = redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
+		Addr:     testConfig.Redis.Addr,
+		Password: testConfig.Redis.Password,
+		DB:       testConfig.Redis.DB,
 	})
 
 	// Wait for Redis to be ready
@@ -68,49 +136,59 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatalf("Redis not ready after 30 seconds: %v", err)
 	}
+}
 
-	// Run tests
-	code := m.Run()
-
-	// Cleanup
-	_, _ = testDB.Exec("DROP TABLE accounts")
+func teardownDatabase() {
+	if migrator, err := db.NewMigrator(testDB, testDialect); err == nil {
+		_, _ = migrator.Down(-1)
+	}
 	_ = testDB.Close()
 	_ = testRedis.Close()
-
-	os.Exit(code)
 }
 
-func setupTest(t *testing.T) (*usecases.TransferMoneyUseCase, func()) {
+// setupTest gives each test an empty backend, of the driver and locking the
+// tests are configured with
+func setupTest(t testing.TB) (*usecases.TransferMoneyUseCase, func()) {
 	t.Helper()
+	return setupTestWithLocking(t, db.Locking(testConfig.Database.Locking))
+}
 
-	// Clear data before each test
-	_, err := testDB.Exec("DELETE FROM accounts")
-	require.NoError(t, err)
-
-	err = testRedis.FlushAll(context.Background()).Err()
-	require.NoError(t, err)
+// setupTestWithLocking is setupTest with another locking than configured
+func setupTestWithLocking(t testing.TB, locking db.Locking) (*usecases.TransferMoneyUseCase, func()) {
+	t.Helper()
 
-	repo := db.NewAccountRepository(testDB, testRedis)
-	useCase := usecases.NewTransferMoneyUseCase(repo)
+	testBackend = backends()[backendName(testConfig.Database.Driver, locking)](t)
+	useCase := usecases.NewTransferMoneyUseCase(testBackend.unitOfWork, testBackend.fxRates, nil)
 
 	cleanup := func() {
-		_, _ = testDB.Exec("DELETE FROM accounts")
-		_ = testRedis.FlushAll(context.Background()).Err()
+		testBackend = nil
 	}
-
 	return useCase, cleanup
 }
 
-func createAccount(t *testing.T, id string, balance int) {
+func createAccount(t testing.TB, id string, balance int) {
 	t.Helper()
-	_, err := testDB.Exec("INSERT INTO accounts (id, balance) VALUES (?, ?)", id, balance)
-	require.NoError(t, err)
+	createAccountIn(t, id, balance, "EUR")
 }
 
-func getAccountBalance(t *testing.T, id string) int {
+func createAccountIn(t testing.TB, id string, balance int, currency string) {
+	t.Helper()
+	account := &banking.Account{ID: id, Balance: balance, Currency: currency, Status: banking.AccountActive}
+	require.NoError(t, testBackend.accounts.Save(t.Context(), account))
+}
+
+// getAccountBalance reads the balance in a transaction, so it never comes from a cache
+func getAccountBalance(t testing.TB, id string) int {
 	t.Helper()
 	var balance int
-	err := testDB.QueryRow("SELECT balance FROM accounts WHERE id = ?", id).Scan(&balance)
+	err := testBackend.unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(t.Context(), id)
+		if err != nil {
+			return err
+		}
+		balance = account.Balance
+		return nil
+	})
 	require.NoError(t, err)
 	return balance
 }
@@ -162,7 +240,7 @@ func TestTransferMoneyUseCase_Execute(t *testing.T) {
 			toID:          "acc7",
 			toBalance:     50,
 			amount:        30,
-			expectedError: "sql: no rows in result set",
+			expectedError: "account not found",
 		},
 	}
 
@@ -178,7 +256,7 @@ func TestTransferMoneyUseCase_Execute(t *testing.T) {
 			createAccount(t, tt.toID, tt.toBalance)
 
 			// Execute transfer
-			err := useCase.Execute(tt.fromID, tt.toID, tt.amount)
+			_, err := useCase.Execute(t.Context(), tt.fromID, tt.toID, banking.NewMoney(tt.amount, "EUR"))
 
 			// Verify results
 			if tt.expectedError != "" {
@@ -197,7 +275,15 @@ func TestTransferMoneyUseCase_Execute(t *testing.T) {
 }
 
 func TestTransferMoneyUseCase_ConcurrentTransfers(t *testing.T) {
-	useCase, cleanup := setupTest(t)
+	for _, locking := range lockingModes {
+		t.Run(string(locking), func(t *testing.T) {
+			testConcurrentTransfers(t, locking)
+		})
+	}
+}
+
+func testConcurrentTransfers(t *testing.T, locking db.Locking) {
+	useCase, cleanup := setupTestWithLocking(t, locking)
 	defer cleanup()
 
 	// Setup test accounts
@@ -214,10 +300,12 @@ func TestTransferMoneyUseCase_ConcurrentTransfers(t *testing.T) {
 	// Start concurrent transfers in both directions
 	for i := 0; i < numTransfers; i++ {
 		go func() {
-			errChan <- useCase.Execute("acc1", "acc2", transferAmount)
+			_, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(transferAmount, "EUR"))
+			errChan <- err
 		}()
 		go func() {
-			errChan <- useCase.Execute("acc2", "acc1", transferAmount)
+			_, err := useCase.Execute(t.Context(), "acc2", "acc1", banking.NewMoney(transferAmount, "EUR"))
+			errChan <- err
 		}()
 	}
 
@@ -238,3 +326,261 @@ func TestTransferMoneyUseCase_ConcurrentTransfers(t *testing.T) {
 	require.Equal(t, 1000, acc1Balance, "Account 1 balance should remain unchanged after bidirectional transfers")
 	require.Equal(t, 1000, acc2Balance, "Account 2 balance should remain unchanged after bidirectional transfers")
 }
+
+func TestTransferMoneyUseCase_RecordsLedgerEntries(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+
+	_, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	fromEntries, err := testBackend.accounts.FindLedgerEntries(t.Context(), "acc1")
+	require.NoError(t, err)
+	toEntries, err := testBackend.accounts.FindLedgerEntries(t.Context(), "acc2")
+	require.NoError(t, err)
+
+	require.Len(t, fromEntries, 1)
+	require.Len(t, toEntries, 1)
+	require.Equal(t, -30, fromEntries[0].Amount)
+	require.Equal(t, 30, toEntries[0].Amount)
+	require.Equal(t, fromEntries[0].JournalID, toEntries[0].JournalID)
+}
+
+func TestTransferMoneyUseCase_RejectsCrossCurrencyTransfers(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccountIn(t, "acc1", 100, "EUR")
+	createAccountIn(t, "acc2", 100, "USD")
+
+	_, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.ErrorIs(t, err, usecases.ErrRateUnavailable, "no rate is quoted for EUR/USD")
+
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "USD"))
+	require.ErrorIs(t, err, banking.ErrCurrencyMismatch)
+
+	require.Equal(t, 100, getAccountBalance(t, "acc1"))
+	require.Equal(t, 100, getAccountBalance(t, "acc2"))
+}
+
+func TestTransferMoneyUseCase_RejectsSameAccount(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+
+	_, err := useCase.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc1", banking.NewMoney(30, "EUR"))
+	require.ErrorIs(t, err, banking.ErrSameAccount)
+	require.Equal(t, 100, getAccountBalance(t, "acc1"))
+}
+
+func TestTransferMoneyUseCase_ConvertsAcrossCurrencies(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccountIn(t, "acc1", 10000, "EUR")
+	createAccountIn(t, "acc2", 0, "JPY")
+	err := testBackend.setRate(banking.Rate{
+		Base:      "EUR",
+		Quote:     "JPY",
+		Value:     big.NewRat(1612345, 10000),
+		SpreadBps: 50,
+		AsOf:      time.Now(),
+	})
+	require.NoError(t, err)
+
+	_, err = useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(1000, "EUR"))
+	require.NoError(t, err)
+
+	// 10.00 EUR at 161.2345 less 0.5% is 1604.283275 JPY, rounded down
+	require.Equal(t, 9000, getAccountBalance(t, "acc1"))
+	require.Equal(t, 1604, getAccountBalance(t, "acc2"))
+
+	// The conversion is stored with its full precision
+	if testDB == nil {
+		return
+	}
+	var rate, spread, rounding string
+	err = testDB.QueryRow("SELECT rate, spread, rounding FROM fx_conversions").Scan(&rate, &spread, &rounding)
+	require.NoError(t, err)
+	require.Equal(t, "161.234500000000", rate)
+	require.Equal(t, "8.061725000000", spread)
+	require.Equal(t, "0.283275000000", rounding)
+}
+
+func TestTransferMoneyUseCase_IdempotencyKeys(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+
+	transfer, err := useCase.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	// A retry replays the original transfer without moving the money again
+	replayed, err := useCase.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, replayed.ID)
+	require.Equal(t, 70, getAccountBalance(t, "acc1"))
+	require.Equal(t, 80, getAccountBalance(t, "acc2"))
+
+	refused, err := useCase.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc2", banking.NewMoney(40, "EUR"))
+	require.ErrorIs(t, err, usecases.ErrIdempotencyKeyReused)
+	require.Equal(t, 70, getAccountBalance(t, "acc1"))
+
+	// A refused request was never attempted, so it is not recorded as failed
+	_, err = usecases.NewGetTransferUseCase(testBackend.transfers).Execute(t.Context(), refused.ID)
+	require.ErrorIs(t, err, banking.ErrTransferNotFound)
+
+	// A failed transfer does not burn its key
+	_, err = useCase.ExecuteIdempotent(t.Context(), "key-2", "acc1", "acc2", banking.NewMoney(500, "EUR"))
+	require.Error(t, err)
+	_, err = useCase.ExecuteIdempotent(t.Context(), "key-2", "acc1", "acc2", banking.NewMoney(50, "EUR"))
+	require.NoError(t, err)
+	require.Equal(t, 20, getAccountBalance(t, "acc1"))
+}
+
+func TestTransferMoneyUseCase_PersistsTransfers(t *testing.T) {
+	useCase, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 50)
+	getTransfer := usecases.NewGetTransferUseCase(testBackend.transfers)
+
+	completed, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	found, err := getTransfer.Execute(t.Context(), completed.ID)
+	require.NoError(t, err)
+	require.Equal(t, banking.TransferCompleted, found.Status)
+	require.Equal(t, banking.NewMoney(30, "EUR"), found.Amount)
+
+	failed, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(500, "EUR"))
+	require.Error(t, err)
+
+	found, err = getTransfer.Execute(t.Context(), failed.ID)
+	require.NoError(t, err)
+	require.Equal(t, banking.TransferFailed, found.Status)
+	require.Equal(t, "insufficient_funds", found.FailureReason)
+
+	_, err = getTransfer.Execute(t.Context(), "unknown")
+	require.ErrorIs(t, err, banking.ErrTransferNotFound)
+}
+
+// cancelingRates cancels the transfer asking for a rate, once it holds both accounts
+type cancelingRates struct {
+	usecases.FXRateProvider
+	cancel context.CancelFunc
+}
+
+func (r cancelingRates) Rate(ctx context.Context, base, quote string) (banking.Rate, error) {
+	r.cancel()
+	return r.FXRateProvider.Rate(ctx, base, quote)
+}
+
+func TestTransferMoneyUseCase_Canceled(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccountIn(t, "acc1", 10000, "EUR")
+	createAccountIn(t, "acc2", 0, "JPY")
+	err := testBackend.setRate(banking.Rate{Base: "EUR", Quote: "JPY", Value: big.NewRat(160, 1), AsOf: time.Now()})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	useCase := usecases.NewTransferMoneyUseCase(testBackend.unitOfWork, cancelingRates{testBackend.fxRates, cancel}, nil)
+	transfer, err := useCase.Execute(ctx, "acc1", "acc2", banking.NewMoney(1000, "EUR"))
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Equal(t, 10000, getAccountBalance(t, "acc1"))
+	require.Equal(t, 0, getAccountBalance(t, "acc2"))
+
+	// The failure is recorded even though the request is gone
+	found, err := usecases.NewGetTransferUseCase(testBackend.transfers).Execute(t.Context(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, banking.TransferFailed, found.Status)
+	require.Equal(t, "canceled", found.FailureReason)
+}
+
+// brokenRates fails like a rate provider whose backend is unreachable
+type brokenRates struct{}
+
+func (brokenRates) Rate(ctx context.Context, base, quote string) (banking.Rate, error) {
+	return banking.Rate{}, errors.New("dial tcp 10.0.0.7:6379: connect: connection refused")
+}
+
+func TestTransferMoneyUseCase_HidesInternalFailures(t *testing.T) {
+	_, cleanup := setupTest(t)
+	defer cleanup()
+
+	createAccountIn(t, "acc1", 10000, "EUR")
+	createAccountIn(t, "acc2", 0, "JPY")
+
+	useCase := usecases.NewTransferMoneyUseCase(testBackend.unitOfWork, brokenRates{}, nil)
+	transfer, err := useCase.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(1000, "EUR"))
+	require.Error(t, err)
+
+	found, err := usecases.NewGetTransferUseCase(testBackend.transfers).Execute(t.Context(), transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, banking.TransferFailed, found.Status)
+	require.Equal(t, "internal", found.FailureReason)
+}
+
+// BenchmarkTransferMoneyUseCase compares transfers that all contend for the
+// same pair of accounts, which the database has to serialize, with transfers
+// spread over disjoint pairs, which run in parallel. Each runs on the row
+// locks of the accounts alone, and serialized by a global mutex as transfers
+// were before, as the baseline they improve on. Only MySQL and Postgres
+// show the difference, as the memory store and SQLite run one transaction
+// at a time either way.
+func BenchmarkTransferMoneyUseCase(b *testing.B) {
+	for _, bc := range []struct {
+		name        string
+		pairs       int
+		globalMutex bool
+	}{
+		{"row locks/shared accounts", 1, false},
+		{"row locks/disjoint accounts", 64, false},
+		{"global mutex/shared accounts", 1, true},
+		{"global mutex/disjoint accounts", 64, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			useCase, cleanup := setupTest(b)
+			defer cleanup()
+
+			for i := 0; i < bc.pairs; i++ {
+				createAccount(b, fmt.Sprintf("from%d", i), 1_000_000_000)
+				createAccount(b, fmt.Sprintf("to%d", i), 0)
+			}
+
+			var mu sync.Mutex
+			transfer := func(from, to string) error {
+				if bc.globalMutex {
+					mu.Lock()
+					defer mu.Unlock()
+				}
+				_, err := useCase.Execute(b.Context(), from, to, banking.NewMoney(1, "EUR"))
+				return err
+			}
+
+			var workers atomic.Int64
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				pair := int(workers.Add(1)) % bc.pairs
+				from, to := fmt.Sprintf("from%d", pair), fmt.Sprintf("to%d", pair)
+				for pb.Next() {
+					if err := transfer(from, to); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
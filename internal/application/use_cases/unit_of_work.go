@@ -0,0 +1,26 @@
+package usecases
+
+import "context"
+
+// Repositories gives access to the repositories within one transaction.
+// Everything read through them is locked until the transaction ends.
+type Repositories interface {
+	Accounts() AccountRepository
+	History() BalanceHistory
+	Transfers() TransferRepository
+	Conversions() ConversionRepository
+	IdempotencyKeys() IdempotencyRepository
+	Outbox() Outbox
+	Webhooks() WebhookRepository
+	WebhookDeliveries() WebhookDeliveryRepository
+	// AfterCommit registers hook to run once the transaction has committed.
+	// Hooks are dropped when the transaction rolls back.
+	AfterCommit(hook func())
+}
+
+// UnitOfWork makes the changes of a use case atomic
+type UnitOfWork interface {
+	// WithinTx runs fn in a transaction, which commits if fn returns nil and
+	// rolls back otherwise. The transaction is abandoned if ctx is canceled.
+	WithinTx(ctx context.Context, fn func(repos Repositories) error) error
+}
@@ -0,0 +1,274 @@
+package usecases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL    = errors.New("invalid webhook URL")
+	ErrWebhookHostForbidden = errors.New("webhook host is not a public address")
+	ErrInvalidEventTypes    = errors.New("invalid event types")
+	ErrInvalidWebhookSecret = errors.New("invalid webhook secret")
+	ErrDeliveryNotDead      = errors.New("webhook delivery is not dead")
+)
+
+// minSecretLength keeps the signatures of a webhook from being guessed
+const minSecretLength = 16
+
+// WebhookEventTypes are the events webhooks can subscribe to
+var WebhookEventTypes = []string{
+	banking.EventMoneyTransferred,
+	banking.EventMoneyTransferFailed,
+	banking.EventAccountDebited,
+	banking.EventAccountCredited,
+}
+
+// Webhook is a partner endpoint events are pushed to
+type Webhook struct {
+	ID         string
+	URL        string
+	EventTypes []string
+	// Secret signs the payloads, so the receiver can tell they come from us
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the webhook receives events of eventType
+func (w *Webhook) Subscribes(eventType string) bool {
+	return slices.Contains(w.EventTypes, eventType)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead deliveries ran out of attempts, and are only tried again on request
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// WebhookDelivery is an event on its way to a webhook. The deliveries of a
+// webhook make up its delivery log.
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	EventID   string
+	EventType string
+	// Payload is the body posted to the webhook
+	Payload       []byte
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	// LastError is why the last attempt failed
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WebhookRepository interface {
+	FindWebhook(ctx context.Context, id string) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	SaveWebhook(ctx context.Context, webhook *Webhook) error
+	// DeleteWebhook deletes the webhook along with its deliveries
+	DeleteWebhook(ctx context.Context, id string) error
+}
+
+type WebhookDeliveryRepository interface {
+	FindDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+	// ListDeliveries returns up to limit deliveries to a webhook, newest
+	// first, only those with status unless it is empty
+	ListDeliveries(ctx context.Context, webhookID string, status DeliveryStatus, limit int) ([]*WebhookDelivery, error)
+	// DueDeliveries returns up to limit pending deliveries due by now, the
+	// longest due first. Within a transaction, they stay locked until it ends.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error
+}
+
+// PublicIP reports whether ip can be reached by webhooks: it is not a
+// loopback, private, link-local (such as the cloud metadata endpoint at
+// 169.254.169.254), unspecified or multicast address
+func PublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+type RegisterWebhookUseCase struct {
+	unitOfWork           UnitOfWork
+	allowPrivateNetworks bool
+}
+
+// Execute registers an http or https URL for some of WebhookEventTypes. Hosts
+// that are not public addresses are refused unless private networks are
+// allowed; names resolving to one are refused by the sender when dialing.
+func (uc *RegisterWebhookUseCase) Execute(ctx context.Context, rawURL string, eventTypes []string, secret string) (*Webhook, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if !uc.allowPrivateNetworks && !publicHost(parsed.Hostname()) {
+		return nil, ErrWebhookHostForbidden
+	}
+	if len(eventTypes) == 0 {
+		return nil, ErrInvalidEventTypes
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(WebhookEventTypes, eventType) {
+			return nil, ErrInvalidEventTypes
+		}
+	}
+	if len(secret) < minSecretLength {
+		return nil, ErrInvalidWebhookSecret
+	}
+
+	webhook := &Webhook{
+		ID:         newID(),
+		URL:        rawURL,
+		EventTypes: slices.Compact(slices.Sorted(slices.Values(eventTypes))),
+		Secret:     secret,
+		CreatedAt:  time.Now().UTC(),
+	}
+	err = uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		return repos.Webhooks().SaveWebhook(ctx, webhook)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// publicHost tells the hosts that are obviously not public apart: local
+// names and IP literals that are not PublicIP
+func publicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return PublicIP(ip)
+	}
+	return true
+}
+
+// NewRegisterWebhookUseCase refuses webhooks on private networks unless
+// allowPrivateNetworks is set
+func NewRegisterWebhookUseCase(unitOfWork UnitOfWork, allowPrivateNetworks bool) *RegisterWebhookUseCase {
+	return &RegisterWebhookUseCase{unitOfWork: unitOfWork, allowPrivateNetworks: allowPrivateNetworks}
+}
+
+type GetWebhookUseCase struct {
+	webhooks WebhookRepository
+}
+
+func (uc *GetWebhookUseCase) Execute(ctx context.Context, id string) (*Webhook, error) {
+	return uc.webhooks.FindWebhook(ctx, id)
+}
+
+func NewGetWebhookUseCase(webhooks WebhookRepository) *GetWebhookUseCase {
+	return &GetWebhookUseCase{webhooks: webhooks}
+}
+
+type ListWebhooksUseCase struct {
+	webhooks WebhookRepository
+}
+
+func (uc *ListWebhooksUseCase) Execute(ctx context.Context) ([]*Webhook, error) {
+	return uc.webhooks.ListWebhooks(ctx)
+}
+
+func NewListWebhooksUseCase(webhooks WebhookRepository) *ListWebhooksUseCase {
+	return &ListWebhooksUseCase{webhooks: webhooks}
+}
+
+type DeleteWebhookUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *DeleteWebhookUseCase) Execute(ctx context.Context, id string) error {
+	return uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		return repos.Webhooks().DeleteWebhook(ctx, id)
+	})
+}
+
+func NewDeleteWebhookUseCase(unitOfWork UnitOfWork) *DeleteWebhookUseCase {
+	return &DeleteWebhookUseCase{unitOfWork: unitOfWork}
+}
+
+type ListWebhookDeliveriesUseCase struct {
+	webhooks   WebhookRepository
+	deliveries WebhookDeliveryRepository
+}
+
+// Execute returns the latest deliveries to a webhook, only those with status
+// unless it is empty. The limit is capped as the page size of ListAccounts.
+func (uc *ListWebhookDeliveriesUseCase) Execute(ctx context.Context, webhookID string, status DeliveryStatus, limit int) ([]*WebhookDelivery, error) {
+	if _, err := uc.webhooks.FindWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	return uc.deliveries.ListDeliveries(ctx, webhookID, status, limit)
+}
+
+func NewListWebhookDeliveriesUseCase(webhooks WebhookRepository, deliveries WebhookDeliveryRepository) *ListWebhookDeliveriesUseCase {
+	return &ListWebhookDeliveriesUseCase{webhooks: webhooks, deliveries: deliveries}
+}
+
+type RedeliverWebhookUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+// Execute takes a dead delivery out of the dead letters, to be tried again
+// with as many attempts as a new one
+func (uc *RedeliverWebhookUseCase) Execute(ctx context.Context, webhookID, deliveryID string) (*WebhookDelivery, error) {
+	var delivery *WebhookDelivery
+	err := uc.unitOfWork.WithinTx(ctx, func(repos Repositories) error {
+		var err error
+		if delivery, err = repos.WebhookDeliveries().FindDelivery(ctx, deliveryID); err != nil {
+			return err
+		}
+		if delivery.WebhookID != webhookID {
+			return ErrDeliveryNotFound
+		}
+		if delivery.Status != DeliveryDead {
+			return ErrDeliveryNotDead
+		}
+
+		now := time.Now().UTC()
+		delivery.Status = DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = now
+		delivery.UpdatedAt = now
+		return repos.WebhookDeliveries().SaveDelivery(ctx, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func NewRedeliverWebhookUseCase(unitOfWork UnitOfWork) *RedeliverWebhookUseCase {
+	return &RedeliverWebhookUseCase{unitOfWork: unitOfWork}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,295 @@
+package usecases_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const webhookSecret = "0123456789abcdef"
+
+// receiver is a partner endpoint, answering with status and checking the
+// signature of every request it gets
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	status   int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header  http.Header
+	payload map[string]any
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	require.NoError(rc.t, err)
+
+	timestamp, err := strconv.ParseInt(r.Header.Get(services.HeaderWebhookTimestamp), 10, 64)
+	require.NoError(rc.t, err)
+	assert.Equal(rc.t, services.SignWebhook(webhookSecret, timestamp, body), r.Header.Get(services.HeaderWebhookSignature))
+
+	var payload map[string]any
+	require.NoError(rc.t, json.Unmarshal(body, &payload))
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, receivedRequest{header: r.Header, payload: payload})
+	w.WriteHeader(rc.status)
+}
+
+func (rc *receiver) setStatus(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func (rc *receiver) received() []receivedRequest {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.requests
+}
+
+func newReceiver(t *testing.T) (*receiver, string) {
+	rc := &receiver{t: t, status: http.StatusOK}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+	return rc, server.URL
+}
+
+// webhookTest wires the use cases moving events from the outbox to the webhooks
+type webhookTest struct {
+	transferMoney *usecases.TransferMoneyUseCase
+	register      *usecases.RegisterWebhookUseCase
+	relay         *usecases.RelayEventsUseCase
+	deliver       *usecases.DeliverWebhooksUseCase
+	deliveries    *usecases.ListWebhookDeliveriesUseCase
+}
+
+var webhookRetryPolicy = usecases.WebhookRetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     time.Millisecond,
+	MaxBackoff:  2 * time.Millisecond,
+}
+
+// setupWebhookTest allows private networks, as receivers listen on loopback
+func setupWebhookTest(t *testing.T) *webhookTest {
+	t.Helper()
+	transferMoney, cleanup := setupTest(t)
+	t.Cleanup(cleanup)
+
+	unitOfWork := testBackend.unitOfWork
+	return &webhookTest{
+		transferMoney: transferMoney,
+		register:      usecases.NewRegisterWebhookUseCase(unitOfWork, true),
+		relay:         usecases.NewRelayEventsUseCase(unitOfWork, nil, 100, time.Minute, usecases.NewScheduleWebhookDeliveriesUseCase()),
+		deliver:       usecases.NewDeliverWebhooksUseCase(unitOfWork, services.NewHTTPWebhookSender(time.Second, true), webhookRetryPolicy, 10, time.Minute),
+		deliveries:    usecases.NewListWebhookDeliveriesUseCase(testBackend.webhooks, testBackend.webhooks),
+	}
+}
+
+// deliverAfterBackoff waits for failed deliveries to be due again
+func (wt *webhookTest) deliverAfterBackoff(t *testing.T) int {
+	t.Helper()
+	time.Sleep(5 * webhookRetryPolicy.MaxBackoff)
+	attempted, err := wt.deliver.Execute(t.Context())
+	require.NoError(t, err)
+	return attempted
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	wt := setupWebhookTest(t)
+	rc, url := newReceiver(t)
+
+	webhook, err := wt.register.Execute(t.Context(), url, []string{banking.EventMoneyTransferred, banking.EventMoneyTransferFailed}, webhookSecret)
+	require.NoError(t, err)
+
+	createAccount(t, "acc1", 100)
+	createAccount(t, "acc2", 0)
+	completed, err := wt.transferMoney.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+	failed, err := wt.transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(500, "EUR"))
+	require.ErrorIs(t, err, banking.ErrInsufficientFunds)
+	// A refused request never ran, so partners are not told it failed
+	_, err = wt.transferMoney.ExecuteIdempotent(t.Context(), "key-1", "acc1", "acc2", banking.NewMoney(40, "EUR"))
+	require.ErrorIs(t, err, usecases.ErrIdempotencyKeyReused)
+
+	relayed, err := wt.relay.Execute(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 4, relayed)
+
+	attempted, err := wt.deliver.Execute(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 2, attempted)
+
+	requests := rc.received()
+	require.Len(t, requests, 2)
+	transfers := map[string]string{}
+	for _, request := range requests {
+		eventType := request.payload["event_type"].(string)
+		assert.Equal(t, eventType, request.header.Get(services.HeaderWebhookEvent))
+		assert.Equal(t, "application/json", request.header.Get("Content-Type"))
+		transfers[eventType] = request.payload["data"].(map[string]any)["transfer_id"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		banking.EventMoneyTransferred:    completed.ID,
+		banking.EventMoneyTransferFailed: failed.ID,
+	}, transfers)
+
+	deliveries, err := wt.deliveries.Execute(t.Context(), webhook.ID, usecases.DeliveryDelivered, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(t, 1, delivery.Attempts)
+	}
+
+	// Nothing is left to deliver, nor delivered twice
+	attempted, err = wt.deliver.Execute(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, attempted)
+	require.Len(t, rc.received(), 2)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	wt := setupWebhookTest(t)
+	rc, url := newReceiver(t)
+	rc.setStatus(http.StatusInternalServerError)
+
+	webhook, err := wt.register.Execute(t.Context(), url, []string{banking.EventAccountCredited}, webhookSecret)
+	require.NoError(t, err)
+
+	createAccount(t, "acc1", 100)
+	_, err = usecases.NewDepositUseCase(testBackend.unitOfWork).Execute(t.Context(), "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+	_, err = wt.relay.Execute(t.Context())
+	require.NoError(t, err)
+
+	for range webhookRetryPolicy.MaxAttempts {
+		require.Equal(t, 1, wt.deliverAfterBackoff(t))
+	}
+	require.Zero(t, wt.deliverAfterBackoff(t), "a dead delivery is not attempted again")
+	require.Len(t, rc.received(), webhookRetryPolicy.MaxAttempts)
+
+	dead, err := wt.deliveries.Execute(t.Context(), webhook.ID, usecases.DeliveryDead, 0)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, webhookRetryPolicy.MaxAttempts, dead[0].Attempts)
+	assert.Contains(t, dead[0].LastError, "500")
+
+	rc.setStatus(http.StatusNoContent)
+	_, err = usecases.NewRedeliverWebhookUseCase(testBackend.unitOfWork).Execute(t.Context(), webhook.ID, dead[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, wt.deliverAfterBackoff(t))
+
+	delivered, err := wt.deliveries.Execute(t.Context(), webhook.ID, usecases.DeliveryDelivered, 0)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+
+	_, err = usecases.NewRedeliverWebhookUseCase(testBackend.unitOfWork).Execute(t.Context(), webhook.ID, dead[0].ID)
+	require.ErrorIs(t, err, usecases.ErrDeliveryNotDead)
+}
+
+func TestWebhookDeleted(t *testing.T) {
+	wt := setupWebhookTest(t)
+	rc, url := newReceiver(t)
+
+	webhook, err := wt.register.Execute(t.Context(), url, []string{banking.EventAccountCredited}, webhookSecret)
+	require.NoError(t, err)
+
+	createAccount(t, "acc1", 100)
+	_, err = usecases.NewDepositUseCase(testBackend.unitOfWork).Execute(t.Context(), "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+	_, err = wt.relay.Execute(t.Context())
+	require.NoError(t, err)
+
+	require.NoError(t, usecases.NewDeleteWebhookUseCase(testBackend.unitOfWork).Execute(t.Context(), webhook.ID))
+	attempted, err := wt.deliver.Execute(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, attempted)
+	require.Empty(t, rc.received())
+
+	_, err = wt.deliveries.Execute(t.Context(), webhook.ID, "", 0)
+	require.ErrorIs(t, err, usecases.ErrWebhookNotFound)
+	err = usecases.NewDeleteWebhookUseCase(testBackend.unitOfWork).Execute(t.Context(), webhook.ID)
+	require.ErrorIs(t, err, usecases.ErrWebhookNotFound)
+}
+
+func TestRegisterWebhook_Invalid(t *testing.T) {
+	wt := setupWebhookTest(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []string
+		secret     string
+		wantErr    error
+	}{
+		{"relative URL", "/hooks", []string{banking.EventMoneyTransferred}, webhookSecret, usecases.ErrInvalidWebhookURL},
+		{"unsupported scheme", "ftp://example.com", []string{banking.EventMoneyTransferred}, webhookSecret, usecases.ErrInvalidWebhookURL},
+		{"no event types", "https://example.com", nil, webhookSecret, usecases.ErrInvalidEventTypes},
+		{"unknown event type", "https://example.com", []string{"AccountOpened"}, webhookSecret, usecases.ErrInvalidEventTypes},
+		{"short secret", "https://example.com", []string{banking.EventMoneyTransferred}, "secret", usecases.ErrInvalidWebhookSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := wt.register.Execute(t.Context(), tt.url, tt.eventTypes, tt.secret)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+
+	webhooks, err := usecases.NewListWebhooksUseCase(testBackend.webhooks).Execute(t.Context())
+	require.NoError(t, err)
+	require.Empty(t, webhooks)
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	wt := setupWebhookTest(t)
+	rc, url := newReceiver(t)
+	register := usecases.NewRegisterWebhookUseCase(testBackend.unitOfWork, false)
+
+	for _, target := range []string{
+		url,
+		"http://localhost:8080/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/hooks",
+		"https://192.168.1.10/hooks",
+		"http://[::1]/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		_, err := register.Execute(t.Context(), target, []string{banking.EventAccountCredited}, webhookSecret)
+		require.ErrorIs(t, err, usecases.ErrWebhookHostForbidden, target)
+	}
+
+	// The sender refuses them too, wherever the name of a webhook leads
+	webhook, err := wt.register.Execute(t.Context(), url, []string{banking.EventAccountCredited}, webhookSecret)
+	require.NoError(t, err)
+	createAccount(t, "acc1", 100)
+	_, err = usecases.NewDepositUseCase(testBackend.unitOfWork).Execute(t.Context(), "acc1", banking.NewMoney(10, "EUR"))
+	require.NoError(t, err)
+	_, err = wt.relay.Execute(t.Context())
+	require.NoError(t, err)
+
+	deliver := usecases.NewDeliverWebhooksUseCase(testBackend.unitOfWork, services.NewHTTPWebhookSender(time.Second, false), webhookRetryPolicy, 10, time.Minute)
+	attempted, err := deliver.Execute(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, 1, attempted)
+	require.Empty(t, rc.received())
+
+	pending, err := wt.deliveries.Execute(t.Context(), webhook.ID, usecases.DeliveryPending, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Contains(t, pending[0].LastError, usecases.ErrWebhookHostForbidden.Error())
+}
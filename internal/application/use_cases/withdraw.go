@@ -0,0 +1,24 @@
+package usecases
+
+import (
+	"context"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+type WithdrawUseCase struct {
+	unitOfWork UnitOfWork
+}
+
+func (uc *WithdrawUseCase) Execute(ctx context.Context, accountID string, amount banking.Money) (*banking.Account, error) {
+	return updateAccount(ctx, uc.unitOfWork, accountID, func(account *banking.Account) error {
+		if amount.Currency != account.Currency {
+			return banking.ErrCurrencyMismatch
+		}
+		return banking.Withdraw(account, amount.Amount)
+	})
+}
+
+func NewWithdrawUseCase(unitOfWork UnitOfWork) *WithdrawUseCase {
+	return &WithdrawUseCase{unitOfWork: unitOfWork}
+}
@@ -1,14 +1,28 @@
 package banking
 
 import (
-	"errors"
 	"sync"
+	"time"
 )
 
 type Account struct {
-	ID      string
-	Balance int
+	ID       string
+	Owner    string
+	Balance  int
+	Currency string
+	Status   AccountStatus
+	// Version counts the times the account was saved, so a stale copy
+	// cannot overwrite a newer one. It is zero until the first save.
+	Version int
 	mu      sync.Mutex
+	entries []LedgerEntry
+	changes []StatusChange
+	events  []Event
+}
+
+// Money returns the balance of the account in its currency
+func (a *Account) Money() Money {
+	return NewMoney(a.Balance, a.Currency)
 }
 
 func (a *Account) Lock() {
@@ -20,29 +34,81 @@ func (a *Account) Unlock() {
 }
 
 func Deposit(account *Account, amount int) error {
-	if amount <= 0 {
-		return errors.New("invalid amount")
+	if err := credit(account, amount); err != nil {
+		return err
 	}
 
-	account.Balance += amount
+	journalID, now := newID(), time.Now().UTC()
+	account.record(
+		newEntry(journalID, account.ID, amount, account.Currency, now),
+		newEntry(journalID, ExternalAccountID, -amount, account.Currency, now),
+	)
 	return nil
 }
 
 func Withdraw(account *Account, amount int) error {
-	if account.Balance < amount {
-		return errors.New("insufficient balance")
+	if err := debit(account, amount); err != nil {
+		return err
 	}
 
-	if amount <= 0 {
-		return errors.New("invalid amount")
+	journalID, now := newID(), time.Now().UTC()
+	account.record(
+		newEntry(journalID, account.ID, -amount, account.Currency, now),
+		newEntry(journalID, ExternalAccountID, amount, account.Currency, now),
+	)
+	return nil
+}
+
+func Transfer(from *Account, to *Account, amount int) error {
+	unlock := lockPair(from, to)
+	defer unlock()
+
+	if from.Currency != to.Currency {
+		return ErrCurrencyMismatch
 	}
 
-	account.Balance -= amount
+	return transfer(from, to, amount)
+}
+
+// TransferWithConversion moves money between accounts held in different
+// currencies. The FX ledger account takes the other side of both legs, so
+// each currency balances on its own, and the journal shares the conversion's ID.
+func TransferWithConversion(from *Account, to *Account, conversion Conversion) error {
+	unlock := lockPair(from, to)
+	defer unlock()
+
+	if err := conversion.validate(from, to); err != nil {
+		return err
+	}
+
+	if err := to.ensureOpen(); err != nil {
+		return err
+	}
+
+	if err := debit(from, conversion.Source.Amount); err != nil {
+		return err
+	}
+	if err := credit(to, conversion.Converted.Amount); err != nil {
+		return err
+	}
+
+	journalID, now := conversion.ID, time.Now().UTC()
+	if journalID == "" {
+		journalID = newID()
+	}
+	from.record(
+		newEntry(journalID, from.ID, -conversion.Source.Amount, from.Currency, now),
+		newEntry(journalID, FXAccountID, conversion.Source.Amount, from.Currency, now),
+	)
+	to.record(
+		newEntry(journalID, FXAccountID, -conversion.Converted.Amount, to.Currency, now),
+		newEntry(journalID, to.ID, conversion.Converted.Amount, to.Currency, now),
+	)
 	return nil
 }
 
-func Transfer(from *Account, to *Account, amount int) error {
-	// Lock accounts in a consistent order to prevent deadlocks
+// Lock accounts in a consistent order to prevent deadlocks
+func lockPair(from *Account, to *Account) func() {
 	firstAccount, secondAccount := from, to
 	if from.ID > to.ID {
 		firstAccount, secondAccount = to, from
@@ -50,16 +116,58 @@ func Transfer(from *Account, to *Account, amount int) error {
 
 	firstAccount.Lock()
 	secondAccount.Lock()
-	defer firstAccount.Unlock()
-	defer secondAccount.Unlock()
-
-	return transfer(from, to, amount)
+	return func() {
+		secondAccount.Unlock()
+		firstAccount.Unlock()
+	}
 }
 
 // private helper function to perform the actual transfer
 func transfer(from *Account, to *Account, amount int) error {
-	if err := Withdraw(from, amount); err != nil {
+	// Check the destination first so a refused credit never leaves a debit behind
+	if err := to.ensureOpen(); err != nil {
+		return err
+	}
+
+	if err := debit(from, amount); err != nil {
+		return err
+	}
+	if err := credit(to, amount); err != nil {
+		return err
+	}
+
+	journalID, now := newID(), time.Now().UTC()
+	from.record(newEntry(journalID, from.ID, -amount, from.Currency, now))
+	to.record(newEntry(journalID, to.ID, amount, to.Currency, now))
+	return nil
+}
+
+func credit(account *Account, amount int) error {
+	if err := account.ensureOpen(); err != nil {
+		return err
+	}
+
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	account.Balance += amount
+	return nil
+}
+
+func debit(account *Account, amount int) error {
+	if err := account.ensureOpen(); err != nil {
 		return err
 	}
-	return Deposit(to, amount)
+
+	if account.Balance < amount {
+		return ErrInsufficientFunds
+	}
+
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	account.Balance -= amount
+	return nil
 }
@@ -1,6 +1,7 @@
 package banking_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/ppicom/newtonian/internal/domain/banking"
@@ -176,3 +177,26 @@ func TestTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestTransferErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int
+		wantErr error
+	}{
+		{name: "insufficient balance", amount: 150, wantErr: banking.ErrInsufficientFunds},
+		{name: "zero transfer", amount: 0, wantErr: banking.ErrInvalidAmount},
+		{name: "negative transfer", amount: -30, wantErr: banking.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &banking.Account{ID: "acc1", Balance: 100}
+			to := &banking.Account{ID: "acc2", Balance: 50}
+
+			if err := banking.Transfer(from, to, tt.amount); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Transfer() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
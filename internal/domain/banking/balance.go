@@ -0,0 +1,47 @@
+package banking
+
+import "time"
+
+// DailyBalance is the balance an account ended a day with, in UTC. It is
+// kept up to date as the account is saved during the day.
+type DailyBalance struct {
+	AccountID string
+	// Day is the midnight starting the day
+	Day     time.Time
+	Balance int
+}
+
+// End is when the day of the balance is over
+func (b DailyBalance) End() time.Time {
+	return b.Day.AddDate(0, 0, 1)
+}
+
+// StartOfDay returns the midnight, in UTC, starting the day of t
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// DailyBalance returns the balance of the account at the end of the day of
+// its latest pending entry, or of today when it has none
+func (a *Account) DailyBalance() DailyBalance {
+	at := time.Now()
+	if len(a.entries) > 0 {
+		at = a.entries[len(a.entries)-1].CreatedAt
+	}
+	return DailyBalance{AccountID: a.ID, Day: StartOfDay(at), Balance: a.Balance}
+}
+
+// BalanceAt works backwards from a later balance of an account using every
+// entry written from at until then, to the balance it had at that time,
+// before any entry written at it. Like NewStatement, it also holds for
+// balances that predate the ledger.
+func BalanceAt(accountID string, later int, at time.Time, since []LedgerEntry) int {
+	balance := later
+	for _, entry := range since {
+		if entry.AccountID == accountID && !entry.CreatedAt.Before(at) {
+			balance -= entry.Amount
+		}
+	}
+	return balance
+}
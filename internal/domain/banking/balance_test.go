@@ -0,0 +1,65 @@
+package banking_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestBalanceAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	since := []banking.LedgerEntry{
+		{AccountID: "acc1", Amount: 50, CreatedAt: day(2)},
+		{AccountID: "external", Amount: -50, CreatedAt: day(2)},
+		{AccountID: "acc1", Amount: -30, CreatedAt: day(3)},
+	}
+
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{day(1), 100},
+		{day(2), 100},
+		{day(2).Add(time.Nanosecond), 150},
+		{day(4), 120},
+	}
+
+	for _, tt := range tests {
+		if got := banking.BalanceAt("acc1", 120, tt.at, since); got != tt.want {
+			t.Errorf("BalanceAt(%v) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestDailyBalance(t *testing.T) {
+	account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+
+	today := banking.StartOfDay(time.Now())
+	if daily := account.DailyBalance(); !daily.Day.Equal(today) || daily.Balance != 100 {
+		t.Errorf("DailyBalance() = %+v, want 100 on %v", daily, today)
+	}
+
+	if err := banking.Deposit(account, 20); err != nil {
+		t.Fatalf("Deposit() error = %v", err)
+	}
+	entries := account.PendingEntries()
+	daily := account.DailyBalance()
+	if !daily.Day.Equal(banking.StartOfDay(entries[len(entries)-1].CreatedAt)) || daily.Balance != 120 {
+		t.Errorf("DailyBalance() = %+v, want 120 on the day of the deposit", daily)
+	}
+
+	if end := daily.End(); end.Sub(daily.Day) != 24*time.Hour {
+		t.Errorf("End() = %v, want a day after %v", end, daily.Day)
+	}
+}
+
+func TestStartOfDay(t *testing.T) {
+	cet := time.FixedZone("CET", 3600)
+	at := time.Date(2024, 2, 1, 0, 30, 0, 0, cet)
+
+	want := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	if got := banking.StartOfDay(at); !got.Equal(want) || got.Location() != time.UTC {
+		t.Errorf("StartOfDay(%v) = %v, want %v", at, got, want)
+	}
+}
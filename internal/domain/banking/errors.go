@@ -0,0 +1,23 @@
+package banking
+
+import "errors"
+
+var (
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	ErrInvalidOwner      = errors.New("invalid owner")
+	ErrInvalidReason     = errors.New("invalid reason")
+	ErrInvalidSpread     = errors.New("invalid spread")
+	ErrInsufficientFunds = errors.New("insufficient balance")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+	ErrSameAccount       = errors.New("cannot transfer to the same account")
+
+	ErrAccountNotFound  = errors.New("account not found")
+	ErrTransferNotFound = errors.New("transfer not found")
+
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountNotFrozen  = errors.New("account is not frozen")
+	ErrAccountClosed     = errors.New("account is closed")
+	ErrAccountNotEmpty   = errors.New("account balance is not zero")
+	ErrInvalidTransition = errors.New("invalid transfer status transition")
+)
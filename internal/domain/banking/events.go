@@ -0,0 +1,212 @@
+package banking
+
+import "time"
+
+// Event types
+const (
+	EventMoneyTransferred    = "MoneyTransferred"
+	EventMoneyTransferFailed = "MoneyTransferFailed"
+	EventAccountDebited      = "AccountDebited"
+	EventAccountCredited     = "AccountCredited"
+	EventAccountOpened       = "AccountOpened"
+	EventAccountFrozen       = "AccountFrozen"
+	EventAccountUnfrozen     = "AccountUnfrozen"
+	EventAccountClosed       = "AccountClosed"
+)
+
+// Event is something that happened to an account, which other services can
+// learn about once it is committed
+type Event interface {
+	Metadata() EventMetadata
+	EventType() string
+	// OrderingKey is the account the event happened to. Consumers see the
+	// events of an account in the order they happened.
+	OrderingKey() string
+}
+
+// EventMetadata identifies an event, so consumers can drop the copies they
+// already handled, and dates it
+type EventMetadata struct {
+	EventID    string    `json:"event_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+func (m EventMetadata) Metadata() EventMetadata {
+	return m
+}
+
+func newEventMetadata(occurredAt time.Time) EventMetadata {
+	return EventMetadata{EventID: newID(), OccurredAt: occurredAt}
+}
+
+// MoneyTransferred is raised when a transfer completes. It is ordered with
+// the events of the account the money left.
+type MoneyTransferred struct {
+	EventMetadata
+	TransferID   string `json:"transfer_id"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	Amount       Money  `json:"amount"`
+	ConversionID string `json:"conversion_id"`
+}
+
+func (e MoneyTransferred) EventType() string {
+	return EventMoneyTransferred
+}
+
+func (e MoneyTransferred) OrderingKey() string {
+	return e.From
+}
+
+// MoneyTransferFailed is raised when a transfer does not go through. It is
+// ordered with the events of the account the money would have left.
+type MoneyTransferFailed struct {
+	EventMetadata
+	TransferID string `json:"transfer_id"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Amount     Money  `json:"amount"`
+	Reason     string `json:"reason"`
+}
+
+func (e MoneyTransferFailed) EventType() string {
+	return EventMoneyTransferFailed
+}
+
+func (e MoneyTransferFailed) OrderingKey() string {
+	return e.From
+}
+
+// AccountDebited is raised when money leaves an account
+type AccountDebited struct {
+	EventMetadata
+	AccountID string `json:"account_id"`
+	JournalID string `json:"journal_id"`
+	Amount    Money  `json:"amount"`
+	// Balance is the balance of the account once debited
+	Balance int `json:"balance"`
+}
+
+func (e AccountDebited) EventType() string {
+	return EventAccountDebited
+}
+
+func (e AccountDebited) OrderingKey() string {
+	return e.AccountID
+}
+
+// AccountCredited is raised when money enters an account
+type AccountCredited struct {
+	EventMetadata
+	AccountID string `json:"account_id"`
+	JournalID string `json:"journal_id"`
+	Amount    Money  `json:"amount"`
+	// Balance is the balance of the account once credited
+	Balance int `json:"balance"`
+}
+
+func (e AccountCredited) EventType() string {
+	return EventAccountCredited
+}
+
+func (e AccountCredited) OrderingKey() string {
+	return e.AccountID
+}
+
+// AccountOpened is raised when an account is opened, active and empty
+type AccountOpened struct {
+	EventMetadata
+	AccountID string `json:"account_id"`
+	Owner     string `json:"owner"`
+	Currency  string `json:"currency"`
+	Actor     string `json:"actor"`
+}
+
+func (e AccountOpened) EventType() string {
+	return EventAccountOpened
+}
+
+func (e AccountOpened) OrderingKey() string {
+	return e.AccountID
+}
+
+// AccountStatusChanged is raised when an account is frozen, unfrozen or
+// closed. Its event type is named after the status the account is left in.
+type AccountStatusChanged struct {
+	EventMetadata
+	AccountID string        `json:"account_id"`
+	From      AccountStatus `json:"from"`
+	To        AccountStatus `json:"to"`
+	Reason    string        `json:"reason"`
+	Actor     string        `json:"actor"`
+}
+
+func (e AccountStatusChanged) EventType() string {
+	switch e.To {
+	case AccountFrozen:
+		return EventAccountFrozen
+	case AccountClosed:
+		return EventAccountClosed
+	}
+	return EventAccountUnfrozen
+}
+
+func (e AccountStatusChanged) OrderingKey() string {
+	return e.AccountID
+}
+
+// balanceChanged is the event of an entry recorded on the account itself
+func (a *Account) balanceChanged(entry LedgerEntry) Event {
+	if entry.Amount < 0 {
+		return AccountDebited{
+			EventMetadata: newEventMetadata(entry.CreatedAt),
+			AccountID:     a.ID,
+			JournalID:     entry.JournalID,
+			Amount:        NewMoney(-entry.Amount, entry.Currency),
+			Balance:       a.Balance,
+		}
+	}
+	return AccountCredited{
+		EventMetadata: newEventMetadata(entry.CreatedAt),
+		AccountID:     a.ID,
+		JournalID:     entry.JournalID,
+		Amount:        NewMoney(entry.Amount, entry.Currency),
+		Balance:       a.Balance,
+	}
+}
+
+// statusChanged is the event of a status change of the account
+func (a *Account) statusChanged(change StatusChange) Event {
+	metadata := newEventMetadata(change.CreatedAt)
+	if change.From == "" && change.To == AccountActive {
+		return AccountOpened{EventMetadata: metadata, AccountID: a.ID, Owner: a.Owner, Currency: a.Currency, Actor: change.Actor}
+	}
+	return AccountStatusChanged{
+		EventMetadata: metadata,
+		AccountID:     a.ID,
+		From:          change.From,
+		To:            change.To,
+		Reason:        change.Reason,
+		Actor:         change.Actor,
+	}
+}
+
+// PendingEvents returns the events raised since the account was last saved
+func (a *Account) PendingEvents() []Event {
+	return a.events
+}
+
+// ClearPendingEvents forgets the raised events once they have been persisted
+func (a *Account) ClearPendingEvents() {
+	a.events = nil
+}
+
+// PendingEvents returns the events raised since the transfer was last saved
+func (t *MoneyTransfer) PendingEvents() []Event {
+	return t.events
+}
+
+// ClearPendingEvents forgets the raised events once they have been persisted
+func (t *MoneyTransfer) ClearPendingEvents() {
+	t.events = nil
+}
@@ -0,0 +1,122 @@
+package banking_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func eventTypes(events []banking.Event) []string {
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.EventType()
+	}
+	return types
+}
+
+func TestAccountEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation func(from, to *banking.Account) error
+		wantFrom  []string
+		wantTo    []string
+	}{
+		{
+			name:      "deposit",
+			operation: func(from, to *banking.Account) error { return banking.Deposit(from, 50) },
+			wantFrom:  []string{banking.EventAccountCredited},
+		},
+		{
+			name:      "withdraw",
+			operation: func(from, to *banking.Account) error { return banking.Withdraw(from, 50) },
+			wantFrom:  []string{banking.EventAccountDebited},
+		},
+		{
+			name:      "transfer",
+			operation: func(from, to *banking.Account) error { return banking.Transfer(from, to, 50) },
+			wantFrom:  []string{banking.EventAccountDebited},
+			wantTo:    []string{banking.EventAccountCredited},
+		},
+		{
+			name:      "failed transfer",
+			operation: func(from, to *banking.Account) error { return banking.Transfer(from, to, 500) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"}
+			to := &banking.Account{ID: "acc2", Balance: 100, Currency: "EUR"}
+			_ = tt.operation(from, to)
+
+			if got := eventTypes(from.PendingEvents()); !slices.Equal(got, tt.wantFrom) {
+				t.Errorf("acc1 events = %v, want %v", got, tt.wantFrom)
+			}
+			if got := eventTypes(to.PendingEvents()); !slices.Equal(got, tt.wantTo) {
+				t.Errorf("acc2 events = %v, want %v", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestBalanceEvents(t *testing.T) {
+	from := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"}
+	to := &banking.Account{ID: "acc2", Balance: 10, Currency: "EUR"}
+	if err := banking.Transfer(from, to, 30); err != nil {
+		t.Fatal(err)
+	}
+
+	debited := from.PendingEvents()[0].(banking.AccountDebited)
+	credited := to.PendingEvents()[0].(banking.AccountCredited)
+	if debited.Amount != banking.NewMoney(30, "EUR") || debited.Balance != 70 || debited.OrderingKey() != "acc1" {
+		t.Errorf("debited = %+v", debited)
+	}
+	if credited.Amount != banking.NewMoney(30, "EUR") || credited.Balance != 40 || credited.OrderingKey() != "acc2" {
+		t.Errorf("credited = %+v", credited)
+	}
+	if debited.JournalID != credited.JournalID || debited.Metadata().EventID == credited.Metadata().EventID {
+		t.Errorf("debited %+v and credited %+v should share their journal only", debited, credited)
+	}
+
+	from.ClearPendingEvents()
+	if len(from.PendingEvents()) != 0 {
+		t.Errorf("events not cleared: %v", from.PendingEvents())
+	}
+}
+
+func TestMoneyTransferredEvent(t *testing.T) {
+	transfer := banking.NewMoneyTransfer("acc1", "acc2", banking.NewMoney(30, "EUR"))
+	if err := transfer.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := transfer.Complete(); err == nil {
+		t.Fatal("completed twice")
+	}
+
+	events := transfer.PendingEvents()
+	if len(events) != 1 {
+		t.Fatalf("events = %v, want one", events)
+	}
+	transferred := events[0].(banking.MoneyTransferred)
+	if transferred.TransferID != transfer.ID || transferred.OrderingKey() != "acc1" || transferred.To != "acc2" {
+		t.Errorf("transferred = %+v", transferred)
+	}
+
+}
+
+func TestMoneyTransferFailedEvent(t *testing.T) {
+	transfer := banking.NewMoneyTransfer("acc1", "acc2", banking.NewMoney(30, "EUR"))
+	if err := transfer.Fail("insufficient balance"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := transfer.PendingEvents()
+	if len(events) != 1 {
+		t.Fatalf("events = %v, want one", events)
+	}
+	failed := events[0].(banking.MoneyTransferFailed)
+	if failed.TransferID != transfer.ID || failed.OrderingKey() != "acc1" || failed.Reason != "insufficient balance" {
+		t.Errorf("failed = %+v", failed)
+	}
+}
@@ -0,0 +1,76 @@
+package banking
+
+import (
+	"math/big"
+	"time"
+)
+
+// Rate is the mid-market price of one unit of Base expressed in Quote, and
+// the spread in basis points charged on top of it.
+type Rate struct {
+	Base      string
+	Quote     string
+	Value     *big.Rat
+	SpreadBps int
+	AsOf      time.Time
+}
+
+// Conversion describes money that changes currency on its way between two
+// accounts. Spread and Rounding are kept in minor units of the converted
+// currency so the conversion can be replayed exactly from its record.
+type Conversion struct {
+	ID        string
+	Source    Money
+	Converted Money
+	Rate      Rate
+	Spread    *big.Rat
+	Rounding  *big.Rat
+}
+
+// Convert applies rate minus its spread to amount and rounds the result down
+// to the minor unit of the quote currency.
+func Convert(amount Money, rate Rate) (Conversion, error) {
+	if amount.Currency != rate.Base {
+		return Conversion{}, ErrCurrencyMismatch
+	}
+
+	if amount.Amount <= 0 || rate.Value == nil || rate.Value.Sign() <= 0 {
+		return Conversion{}, ErrInvalidAmount
+	}
+
+	if rate.SpreadBps < 0 || rate.SpreadBps >= 10000 {
+		return Conversion{}, ErrInvalidSpread
+	}
+
+	// Moving between minor units, e.g. from cents to yen, scales the amount
+	scale := new(big.Rat).Quo(pow10(MinorUnits(rate.Quote)), pow10(MinorUnits(rate.Base)))
+	mid := new(big.Rat).Mul(new(big.Rat).SetInt64(int64(amount.Amount)), rate.Value)
+	mid.Mul(mid, scale)
+
+	exact := new(big.Rat).Mul(mid, big.NewRat(int64(10000-rate.SpreadBps), 10000))
+	converted := new(big.Int).Quo(exact.Num(), exact.Denom())
+
+	return Conversion{
+		ID:        newID(),
+		Source:    amount,
+		Converted: NewMoney(int(converted.Int64()), rate.Quote),
+		Rate:      rate,
+		Spread:    new(big.Rat).Sub(mid, exact),
+		Rounding:  new(big.Rat).Sub(exact, new(big.Rat).SetInt(converted)),
+	}, nil
+}
+
+func (c Conversion) validate(from, to *Account) error {
+	if c.Source.Currency != from.Currency || c.Converted.Currency != to.Currency {
+		return ErrCurrencyMismatch
+	}
+
+	if c.Source.Amount <= 0 || c.Converted.Amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+func pow10(n int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil))
+}
@@ -0,0 +1,107 @@
+package banking_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        banking.Money
+		rate          banking.Rate
+		wantConverted banking.Money
+		wantSpread    string
+		wantRounding  string
+		wantError     bool
+	}{
+		{
+			name:          "same minor unit",
+			amount:        banking.NewMoney(1000, "EUR"),
+			rate:          rate("EUR", "USD", "1.0835", 0),
+			wantConverted: banking.NewMoney(1083, "USD"),
+			wantSpread:    "0.0000",
+			wantRounding:  "0.5000",
+		},
+		{
+			name:          "with spread",
+			amount:        banking.NewMoney(1000, "EUR"),
+			rate:          rate("EUR", "USD", "1.0835", 100),
+			wantConverted: banking.NewMoney(1072, "USD"),
+			wantSpread:    "10.8350",
+			wantRounding:  "0.6650",
+		},
+		{
+			name:          "into a currency without minor unit",
+			amount:        banking.NewMoney(1000, "EUR"),
+			rate:          rate("EUR", "JPY", "161.2345", 0),
+			wantConverted: banking.NewMoney(1612, "JPY"),
+			wantSpread:    "0.0000",
+			wantRounding:  "0.3450",
+		},
+		{
+			name:      "amount in the wrong currency",
+			amount:    banking.NewMoney(1000, "GBP"),
+			rate:      rate("EUR", "USD", "1.0835", 0),
+			wantError: true,
+		},
+		{
+			name:      "zero amount",
+			amount:    banking.NewMoney(0, "EUR"),
+			rate:      rate("EUR", "USD", "1.0835", 0),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion, err := banking.Convert(tt.amount, tt.rate)
+
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Convert() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+
+			if conversion.Converted != tt.wantConverted {
+				t.Errorf("Converted = %v, want %v", conversion.Converted, tt.wantConverted)
+			}
+
+			if got := conversion.Spread.FloatString(4); got != tt.wantSpread {
+				t.Errorf("Spread = %v, want %v", got, tt.wantSpread)
+			}
+
+			if got := conversion.Rounding.FloatString(4); got != tt.wantRounding {
+				t.Errorf("Rounding = %v, want %v", got, tt.wantRounding)
+			}
+		})
+	}
+}
+
+func TestTransferWithConversionSharesJournal(t *testing.T) {
+	from := &banking.Account{ID: "acc1", Balance: 1000, Currency: "EUR"}
+	to := &banking.Account{ID: "acc2", Balance: 0, Currency: "USD"}
+
+	conversion, err := banking.Convert(banking.NewMoney(1000, "EUR"), rate("EUR", "USD", "1.0835", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := banking.TransferWithConversion(from, to, conversion); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range append(from.PendingEntries(), to.PendingEntries()...) {
+		if entry.JournalID != conversion.ID {
+			t.Errorf("JournalID = %v, want %v", entry.JournalID, conversion.ID)
+		}
+	}
+}
+
+func rate(base, quote, value string, spreadBps int) banking.Rate {
+	r, _ := new(big.Rat).SetString(value)
+	return banking.Rate{Base: base, Quote: quote, Value: r, SpreadBps: spreadBps}
+}
@@ -0,0 +1,89 @@
+package banking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// ExternalAccountID is the ledger account on the other side of deposits and
+// withdrawals: money entering or leaving the bank.
+const ExternalAccountID = "external"
+
+// FXAccountID is the ledger account that sits between the two currency legs
+// of a converted transfer.
+const FXAccountID = "fx"
+
+// LedgerEntry is a single line of a double-entry journal. Credits are
+// positive and debits negative, so the entries of a journal sum to zero in
+// each currency.
+type LedgerEntry struct {
+	JournalID string
+	AccountID string
+	Amount    int
+	Currency  string
+	CreatedAt time.Time
+}
+
+func newEntry(journalID, accountID string, amount int, currency string, createdAt time.Time) LedgerEntry {
+	return LedgerEntry{
+		JournalID: journalID,
+		AccountID: accountID,
+		Amount:    amount,
+		Currency:  currency,
+		CreatedAt: createdAt,
+	}
+}
+
+// Balanced reports whether every journal in entries sums to zero in each currency
+func Balanced(entries []LedgerEntry) bool {
+	totals := make(map[[2]string]int)
+	for _, entry := range entries {
+		totals[[2]string{entry.JournalID, entry.Currency}] += entry.Amount
+	}
+
+	for _, total := range totals {
+		if total != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// BalanceOf rebuilds the balance of an account from its ledger history
+func BalanceOf(accountID string, entries []LedgerEntry) int {
+	balance := 0
+	for _, entry := range entries {
+		if entry.AccountID == accountID {
+			balance += entry.Amount
+		}
+	}
+	return balance
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// record keeps entries until the account is saved, raising an event for
+// each one on the account itself
+func (a *Account) record(entries ...LedgerEntry) {
+	a.entries = append(a.entries, entries...)
+	for _, entry := range entries {
+		if entry.AccountID == a.ID {
+			a.events = append(a.events, a.balanceChanged(entry))
+		}
+	}
+}
+
+// PendingEntries returns the ledger entries recorded since the account was last saved
+func (a *Account) PendingEntries() []LedgerEntry {
+	return a.entries
+}
+
+// ClearPendingEntries forgets the recorded entries once they have been persisted
+func (a *Account) ClearPendingEntries() {
+	a.entries = nil
+}
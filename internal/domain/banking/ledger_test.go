@@ -0,0 +1,68 @@
+package banking_test
+
+import (
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestLedgerEntries(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   func(from, to *banking.Account) error
+		wantEntries int
+	}{
+		{
+			name: "deposit",
+			operation: func(from, to *banking.Account) error {
+				return banking.Deposit(from, 50)
+			},
+			wantEntries: 2,
+		},
+		{
+			name: "withdraw",
+			operation: func(from, to *banking.Account) error {
+				return banking.Withdraw(from, 50)
+			},
+			wantEntries: 2,
+		},
+		{
+			name: "transfer",
+			operation: func(from, to *banking.Account) error {
+				return banking.Transfer(from, to, 50)
+			},
+			wantEntries: 2,
+		},
+		{
+			name: "failed transfer",
+			operation: func(from, to *banking.Account) error {
+				return banking.Transfer(from, to, 500)
+			},
+			wantEntries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &banking.Account{ID: "acc1", Balance: 100}
+			to := &banking.Account{ID: "acc2", Balance: 100}
+			_ = tt.operation(from, to)
+
+			entries := append(from.PendingEntries(), to.PendingEntries()...)
+			if len(entries) != tt.wantEntries {
+				t.Fatalf("entries = %v, want %v", len(entries), tt.wantEntries)
+			}
+
+			if !banking.Balanced(entries) {
+				t.Errorf("entries %v do not sum to zero", entries)
+			}
+
+			if got := banking.BalanceOf("acc1", entries); got != from.Balance-100 {
+				t.Errorf("BalanceOf(acc1) = %v, want %v", got, from.Balance-100)
+			}
+			if got := banking.BalanceOf("acc2", entries); got != to.Balance-100 {
+				t.Errorf("BalanceOf(acc2) = %v, want %v", got, to.Balance-100)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package banking
+
+import "time"
+
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	AccountFrozen AccountStatus = "frozen"
+	AccountClosed AccountStatus = "closed"
+)
+
+// StatusChange records who moved an account between statuses and why
+type StatusChange struct {
+	AccountID string
+	From      AccountStatus
+	To        AccountStatus
+	Reason    string
+	Actor     string
+	CreatedAt time.Time
+}
+
+func OpenAccount(owner, currency, actor string) (*Account, error) {
+	if owner == "" {
+		return nil, ErrInvalidOwner
+	}
+
+	if !ValidCurrency(currency) {
+		return nil, ErrInvalidCurrency
+	}
+
+	account := &Account{ID: newID(), Owner: owner, Currency: currency}
+	account.changeStatus(AccountActive, "opened", actor)
+	return account, nil
+}
+
+func Freeze(account *Account, reason, actor string) error {
+	if reason == "" {
+		return ErrInvalidReason
+	}
+
+	if err := account.ensureOpen(); err != nil {
+		return err
+	}
+
+	account.changeStatus(AccountFrozen, reason, actor)
+	return nil
+}
+
+func Unfreeze(account *Account, reason, actor string) error {
+	if reason == "" {
+		return ErrInvalidReason
+	}
+
+	if account.Status != AccountFrozen {
+		return ErrAccountNotFrozen
+	}
+
+	account.changeStatus(AccountActive, reason, actor)
+	return nil
+}
+
+// Close closes an empty account for good, frozen or not
+func Close(account *Account, reason, actor string) error {
+	if reason == "" {
+		return ErrInvalidReason
+	}
+
+	if account.Status == AccountClosed {
+		return ErrAccountClosed
+	}
+
+	if account.Balance != 0 {
+		return ErrAccountNotEmpty
+	}
+
+	account.changeStatus(AccountClosed, reason, actor)
+	return nil
+}
+
+// ensureOpen refuses to move money on frozen or closed accounts. Accounts
+// without a status predate the lifecycle and are considered active.
+func (a *Account) ensureOpen() error {
+	switch a.Status {
+	case AccountFrozen:
+		return ErrAccountFrozen
+	case AccountClosed:
+		return ErrAccountClosed
+	}
+	return nil
+}
+
+// changeStatus records the change until the account is saved, raising its event
+func (a *Account) changeStatus(status AccountStatus, reason, actor string) {
+	change := StatusChange{
+		AccountID: a.ID,
+		From:      a.Status,
+		To:        status,
+		Reason:    reason,
+		Actor:     actor,
+		CreatedAt: time.Now().UTC(),
+	}
+	a.changes = append(a.changes, change)
+	a.events = append(a.events, a.statusChanged(change))
+	a.Status = status
+}
+
+// PendingStatusChanges returns the status changes made since the account was last saved
+func (a *Account) PendingStatusChanges() []StatusChange {
+	return a.changes
+}
+
+// ClearPendingStatusChanges forgets the recorded status changes once they have been persisted
+func (a *Account) ClearPendingStatusChanges() {
+	a.changes = nil
+}
@@ -0,0 +1,116 @@
+package banking_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestOpenAccount(t *testing.T) {
+	account, err := banking.OpenAccount("alice", "EUR", "clerk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if account.ID == "" || account.Status != banking.AccountActive || account.Balance != 0 {
+		t.Errorf("OpenAccount() = %+v, want an empty active account", account)
+	}
+
+	changes := account.PendingStatusChanges()
+	if len(changes) != 1 || changes[0].To != banking.AccountActive || changes[0].Actor != "clerk" {
+		t.Errorf("status changes = %+v, want the opening", changes)
+	}
+
+	if _, err := banking.OpenAccount("alice", "euro", "clerk"); err == nil {
+		t.Error("OpenAccount() with an invalid currency should fail")
+	}
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     banking.AccountStatus
+		balance    int
+		change     func(*banking.Account) error
+		wantStatus banking.AccountStatus
+		wantError  error
+	}{
+		{
+			name:       "freeze an active account",
+			status:     banking.AccountActive,
+			change:     func(a *banking.Account) error { return banking.Freeze(a, "fraud check", "ops") },
+			wantStatus: banking.AccountFrozen,
+		},
+		{
+			name:       "unfreeze a frozen account",
+			status:     banking.AccountFrozen,
+			change:     func(a *banking.Account) error { return banking.Unfreeze(a, "cleared", "ops") },
+			wantStatus: banking.AccountActive,
+		},
+		{
+			name:       "close an empty frozen account",
+			status:     banking.AccountFrozen,
+			change:     func(a *banking.Account) error { return banking.Close(a, "customer request", "ops") },
+			wantStatus: banking.AccountClosed,
+		},
+		{
+			name:       "close an account with money",
+			status:     banking.AccountActive,
+			balance:    10,
+			change:     func(a *banking.Account) error { return banking.Close(a, "customer request", "ops") },
+			wantStatus: banking.AccountActive,
+			wantError:  banking.ErrAccountNotEmpty,
+		},
+		{
+			name:       "freeze a closed account",
+			status:     banking.AccountClosed,
+			change:     func(a *banking.Account) error { return banking.Freeze(a, "fraud check", "ops") },
+			wantStatus: banking.AccountClosed,
+			wantError:  banking.ErrAccountClosed,
+		},
+		{
+			name:       "deposit into a frozen account",
+			status:     banking.AccountFrozen,
+			change:     func(a *banking.Account) error { return banking.Deposit(a, 10) },
+			wantStatus: banking.AccountFrozen,
+			wantError:  banking.ErrAccountFrozen,
+		},
+		{
+			name:       "withdraw from a closed account",
+			status:     banking.AccountClosed,
+			change:     func(a *banking.Account) error { return banking.Withdraw(a, 10) },
+			wantStatus: banking.AccountClosed,
+			wantError:  banking.ErrAccountClosed,
+		},
+		{
+			name:    "transfer into a frozen account",
+			status:  banking.AccountFrozen,
+			balance: 0,
+			change: func(a *banking.Account) error {
+				return banking.Transfer(&banking.Account{ID: "other", Balance: 100}, a, 10)
+			},
+			wantStatus: banking.AccountFrozen,
+			wantError:  banking.ErrAccountFrozen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := &banking.Account{ID: "acc1", Balance: tt.balance, Status: tt.status}
+			err := tt.change(account)
+
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("error = %v, want %v", err, tt.wantError)
+			}
+
+			if account.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", account.Status, tt.wantStatus)
+			}
+
+			if account.Balance != tt.balance {
+				t.Errorf("Balance = %v, want %v", account.Balance, tt.balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package banking
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minorUnits is the number of decimals of the minor unit of each ISO-4217
+// currency that differs from the usual two.
+var minorUnits = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// Money is an amount expressed in the minor unit of its ISO-4217 currency,
+// e.g. cents for EUR or yen for JPY.
+type Money struct {
+	Amount   int    `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func NewMoney(amount int, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ValidCurrency reports whether code looks like an ISO-4217 currency code
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	return strings.ToUpper(code) == code && strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// MinorUnits returns the number of decimals of the currency's minor unit
+func MinorUnits(currency string) int {
+	if units, ok := minorUnits[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+func (m Money) String() string {
+	units := MinorUnits(m.Currency)
+	if units == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	scale := 1
+	for i := 0; i < units; i++ {
+		scale *= 10
+	}
+
+	sign, amount := "", m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/scale, units, amount%scale, m.Currency)
+}
@@ -0,0 +1,103 @@
+package banking_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money banking.Money
+		want  string
+	}{
+		{money: banking.NewMoney(1050, "EUR"), want: "10.50 EUR"},
+		{money: banking.NewMoney(-5, "USD"), want: "-0.05 USD"},
+		{money: banking.NewMoney(1050, "JPY"), want: "1050 JPY"},
+		{money: banking.NewMoney(1050, "KWD"), want: "1.050 KWD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.money.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidCurrency(t *testing.T) {
+	for code, want := range map[string]bool{"EUR": true, "eur": false, "EU": false, "E1R": false, "": false} {
+		if got := banking.ValidCurrency(code); got != want {
+			t.Errorf("ValidCurrency(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestTransferAcrossCurrencies(t *testing.T) {
+	tests := []struct {
+		name            string
+		conversion      *banking.Conversion
+		wantFromBalance int
+		wantToBalance   int
+		wantError       error
+	}{
+		{
+			name:            "without conversion",
+			wantFromBalance: 100,
+			wantToBalance:   50,
+			wantError:       banking.ErrCurrencyMismatch,
+		},
+		{
+			name: "with conversion",
+			conversion: &banking.Conversion{
+				Source:    banking.NewMoney(30, "EUR"),
+				Converted: banking.NewMoney(33, "USD"),
+			},
+			wantFromBalance: 70,
+			wantToBalance:   83,
+		},
+		{
+			name: "conversion in the wrong currencies",
+			conversion: &banking.Conversion{
+				Source:    banking.NewMoney(30, "USD"),
+				Converted: banking.NewMoney(27, "EUR"),
+			},
+			wantFromBalance: 100,
+			wantToBalance:   50,
+			wantError:       banking.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"}
+			to := &banking.Account{ID: "acc2", Balance: 50, Currency: "USD"}
+
+			var err error
+			if tt.conversion == nil {
+				err = banking.Transfer(from, to, 30)
+			} else {
+				err = banking.TransferWithConversion(from, to, *tt.conversion)
+			}
+
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("error = %v, want %v", err, tt.wantError)
+			}
+
+			if from.Balance != tt.wantFromBalance {
+				t.Errorf("From Balance = %v, want %v", from.Balance, tt.wantFromBalance)
+			}
+
+			if to.Balance != tt.wantToBalance {
+				t.Errorf("To Balance = %v, want %v", to.Balance, tt.wantToBalance)
+			}
+
+			entries := append(from.PendingEntries(), to.PendingEntries()...)
+			if !banking.Balanced(entries) {
+				t.Errorf("entries %v are not balanced per currency", entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package banking
+
+// Replay applies to account, in order, the events it raised since the state
+// it is in, such as an empty account or a snapshot of it. The replayed
+// events are not pending again, and the version is left to the caller.
+func Replay(account *Account, events ...Event) {
+	for _, event := range events {
+		account.apply(event)
+	}
+}
+
+func (a *Account) apply(event Event) {
+	switch e := event.(type) {
+	case AccountOpened:
+		a.ID, a.Owner, a.Currency, a.Status = e.AccountID, e.Owner, e.Currency, AccountActive
+	case AccountStatusChanged:
+		a.Status = e.To
+	case AccountCredited:
+		a.Balance += e.Amount.Amount
+	case AccountDebited:
+		a.Balance -= e.Amount.Amount
+	}
+}
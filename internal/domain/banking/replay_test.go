@@ -0,0 +1,76 @@
+package banking_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestReplay(t *testing.T) {
+	account, err := banking.OpenAccount("alice", "EUR", "clerk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := &banking.Account{ID: "other", Currency: "EUR", Status: banking.AccountActive}
+
+	steps := []func() error{
+		func() error { return banking.Deposit(account, 100) },
+		func() error { return banking.Transfer(account, other, 30) },
+		func() error { return banking.Freeze(account, "fraud check", "ops") },
+		func() error { return banking.Unfreeze(account, "cleared", "ops") },
+		func() error { return banking.Withdraw(account, 20) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := account.PendingEvents()
+	want := []string{
+		banking.EventAccountOpened,
+		banking.EventAccountCredited,
+		banking.EventAccountDebited,
+		banking.EventAccountFrozen,
+		banking.EventAccountUnfrozen,
+		banking.EventAccountDebited,
+	}
+	if got := eventTypes(events); !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+
+	replayed := &banking.Account{}
+	banking.Replay(replayed, events...)
+	if replayed.ID != account.ID || replayed.Owner != "alice" || replayed.Currency != "EUR" ||
+		replayed.Status != banking.AccountActive || replayed.Balance != 50 {
+		t.Errorf("replayed = %+v, want %+v", replayed, account)
+	}
+	if len(replayed.PendingEvents()) != 0 || len(replayed.PendingEntries()) != 0 {
+		t.Error("replayed events are pending again")
+	}
+
+	// Replaying the rest of the events on a snapshot gives the same account
+	snapshot := &banking.Account{}
+	banking.Replay(snapshot, events[:3]...)
+	if snapshot.Balance != 70 {
+		t.Errorf("snapshot balance = %d, want 70", snapshot.Balance)
+	}
+	banking.Replay(snapshot, events[3:]...)
+	if snapshot.Balance != replayed.Balance || snapshot.Status != replayed.Status {
+		t.Errorf("from snapshot = %+v, want %+v", snapshot, replayed)
+	}
+}
+
+func TestReplayClosed(t *testing.T) {
+	account := &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}
+	if err := banking.Close(account, "customer request", "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := &banking.Account{ID: "acc1", Currency: "EUR", Status: banking.AccountActive}
+	banking.Replay(replayed, account.PendingEvents()...)
+	if replayed.Status != banking.AccountClosed {
+		t.Errorf("status = %v, want closed", replayed.Status)
+	}
+}
@@ -0,0 +1,43 @@
+package banking
+
+import "time"
+
+// Statement lists the ledger entries of an account within [From, To)
+// together with its balance at both ends.
+type Statement struct {
+	AccountID      string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance int
+	ClosingBalance int
+	Entries        []LedgerEntry
+}
+
+// NewStatement works backwards from the current balance of the account using
+// every entry written since from, so it also holds for balances that predate
+// the ledger.
+func NewStatement(account *Account, from, to time.Time, since []LedgerEntry) Statement {
+	statement := Statement{
+		AccountID:      account.ID,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: account.Balance,
+		ClosingBalance: account.Balance,
+	}
+
+	for _, entry := range since {
+		if entry.AccountID != account.ID || entry.CreatedAt.Before(from) {
+			continue
+		}
+
+		statement.OpeningBalance -= entry.Amount
+		if entry.CreatedAt.Before(to) {
+			statement.Entries = append(statement.Entries, entry)
+		} else {
+			statement.ClosingBalance -= entry.Amount
+		}
+	}
+	return statement
+}
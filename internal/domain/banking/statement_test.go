@@ -0,0 +1,32 @@
+package banking_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestNewStatement(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	account := &banking.Account{ID: "acc1", Balance: 120, Currency: "EUR"}
+	since := []banking.LedgerEntry{
+		{AccountID: "acc1", Amount: 50, CreatedAt: day(2)},
+		{AccountID: "acc1", Amount: -30, CreatedAt: day(3)},
+		{AccountID: "acc1", Amount: 20, CreatedAt: day(5)},
+	}
+
+	statement := banking.NewStatement(account, day(2), day(4), since)
+
+	if statement.OpeningBalance != 80 {
+		t.Errorf("OpeningBalance = %v, want 80", statement.OpeningBalance)
+	}
+
+	if statement.ClosingBalance != 100 {
+		t.Errorf("ClosingBalance = %v, want 100", statement.ClosingBalance)
+	}
+
+	if len(statement.Entries) != 2 {
+		t.Errorf("Entries = %v, want the two entries within the range", statement.Entries)
+	}
+}
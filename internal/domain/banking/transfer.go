@@ -0,0 +1,89 @@
+package banking
+
+import "time"
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferCompleted TransferStatus = "completed"
+	TransferFailed    TransferStatus = "failed"
+	TransferReversed  TransferStatus = "reversed"
+)
+
+// MoneyTransfer is the record of a request to move money between two
+// accounts, kept whether it went through or not.
+type MoneyTransfer struct {
+	ID            string
+	From          string
+	To            string
+	Amount        Money
+	ConversionID  string
+	Status        TransferStatus
+	FailureReason string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	events        []Event
+}
+
+func NewMoneyTransfer(from, to string, amount Money) *MoneyTransfer {
+	now := time.Now().UTC()
+	return &MoneyTransfer{
+		ID:        newID(),
+		From:      from,
+		To:        to,
+		Amount:    amount,
+		Status:    TransferPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Complete raises MoneyTransferred
+func (t *MoneyTransfer) Complete() error {
+	if err := t.transition(TransferPending, TransferCompleted); err != nil {
+		return err
+	}
+
+	t.events = append(t.events, MoneyTransferred{
+		EventMetadata: newEventMetadata(t.UpdatedAt),
+		TransferID:    t.ID,
+		From:          t.From,
+		To:            t.To,
+		Amount:        t.Amount,
+		ConversionID:  t.ConversionID,
+	})
+	return nil
+}
+
+// Fail raises MoneyTransferFailed
+func (t *MoneyTransfer) Fail(reason string) error {
+	if err := t.transition(TransferPending, TransferFailed); err != nil {
+		return err
+	}
+
+	t.FailureReason = reason
+	t.events = append(t.events, MoneyTransferFailed{
+		EventMetadata: newEventMetadata(t.UpdatedAt),
+		TransferID:    t.ID,
+		From:          t.From,
+		To:            t.To,
+		Amount:        t.Amount,
+		Reason:        reason,
+	})
+	return nil
+}
+
+func (t *MoneyTransfer) Reverse() error {
+	return t.transition(TransferCompleted, TransferReversed)
+}
+
+func (t *MoneyTransfer) transition(from, to TransferStatus) error {
+	if t.Status != from {
+		return ErrInvalidTransition
+	}
+
+	t.Status = to
+	t.UpdatedAt = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,72 @@
+package banking_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+func TestMoneyTransferLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		steps      []func(*banking.MoneyTransfer) error
+		wantStatus banking.TransferStatus
+		wantError  error
+	}{
+		{
+			name:       "new transfer",
+			wantStatus: banking.TransferPending,
+		},
+		{
+			name:       "complete",
+			steps:      []func(*banking.MoneyTransfer) error{(*banking.MoneyTransfer).Complete},
+			wantStatus: banking.TransferCompleted,
+		},
+		{
+			name: "fail",
+			steps: []func(*banking.MoneyTransfer) error{func(t *banking.MoneyTransfer) error {
+				return t.Fail("insufficient balance")
+			}},
+			wantStatus: banking.TransferFailed,
+		},
+		{
+			name:       "reverse a completed transfer",
+			steps:      []func(*banking.MoneyTransfer) error{(*banking.MoneyTransfer).Complete, (*banking.MoneyTransfer).Reverse},
+			wantStatus: banking.TransferReversed,
+		},
+		{
+			name:       "reverse a pending transfer",
+			steps:      []func(*banking.MoneyTransfer) error{(*banking.MoneyTransfer).Reverse},
+			wantStatus: banking.TransferPending,
+			wantError:  banking.ErrInvalidTransition,
+		},
+		{
+			name:       "complete twice",
+			steps:      []func(*banking.MoneyTransfer) error{(*banking.MoneyTransfer).Complete, (*banking.MoneyTransfer).Complete},
+			wantStatus: banking.TransferCompleted,
+			wantError:  banking.ErrInvalidTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := banking.NewMoneyTransfer("acc1", "acc2", banking.NewMoney(30, "EUR"))
+
+			var err error
+			for _, step := range tt.steps {
+				if err = step(transfer); err != nil {
+					break
+				}
+			}
+
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("error = %v, want %v", err, tt.wantError)
+			}
+
+			if transfer.Status != tt.wantStatus {
+				t.Errorf("Status = %v, want %v", transfer.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain identifies the errors of this service in gRPC error details
+const ErrorDomain = "banking.v1"
+
+// ErrMalformedRequest covers request fields the transport layer cannot parse
+var ErrMalformedRequest = errors.New("malformed request")
+
+// StatusClientClosedRequest is the non-standard HTTP status of requests
+// canceled by their client, which will never read the response
+const StatusClientClosedRequest = 499
+
+// Error is how a failure is reported to clients: a machine-readable code,
+// plus the HTTP status and gRPC code that carry it.
+type Error struct {
+	Code       string
+	Message    string
+	HTTPStatus int
+	GRPCCode   codes.Code
+}
+
+type mapping struct {
+	err        error
+	code       string
+	httpStatus int
+	grpcCode   codes.Code
+}
+
+var mappings = []mapping{
+	{ErrMalformedRequest, "malformed_request", http.StatusBadRequest, codes.InvalidArgument},
+	{banking.ErrInvalidAmount, "invalid_amount", http.StatusBadRequest, codes.InvalidArgument},
+	{banking.ErrInvalidCurrency, "invalid_currency", http.StatusBadRequest, codes.InvalidArgument},
+	{banking.ErrInvalidOwner, "invalid_owner", http.StatusBadRequest, codes.InvalidArgument},
+	{banking.ErrInvalidReason, "invalid_reason", http.StatusBadRequest, codes.InvalidArgument},
+	{banking.ErrSameAccount, "same_account", http.StatusBadRequest, codes.InvalidArgument},
+	{usecases.ErrInvalidDateRange, "invalid_date_range", http.StatusBadRequest, codes.InvalidArgument},
+	{usecases.ErrInvalidWebhookURL, "invalid_webhook_url", http.StatusBadRequest, codes.InvalidArgument},
+	{usecases.ErrWebhookHostForbidden, "webhook_host_forbidden", http.StatusBadRequest, codes.InvalidArgument},
+	{usecases.ErrInvalidEventTypes, "invalid_event_types", http.StatusBadRequest, codes.InvalidArgument},
+	{usecases.ErrInvalidWebhookSecret, "invalid_webhook_secret", http.StatusBadRequest, codes.InvalidArgument},
+
+	{banking.ErrAccountNotFound, "account_not_found", http.StatusNotFound, codes.NotFound},
+	{banking.ErrTransferNotFound, "transfer_not_found", http.StatusNotFound, codes.NotFound},
+	{usecases.ErrWebhookNotFound, "webhook_not_found", http.StatusNotFound, codes.NotFound},
+	{usecases.ErrDeliveryNotFound, "delivery_not_found", http.StatusNotFound, codes.NotFound},
+
+	{banking.ErrAccountFrozen, "account_frozen", http.StatusConflict, codes.FailedPrecondition},
+	{banking.ErrAccountNotFrozen, "account_not_frozen", http.StatusConflict, codes.FailedPrecondition},
+	{banking.ErrAccountClosed, "account_closed", http.StatusConflict, codes.FailedPrecondition},
+	{banking.ErrAccountNotEmpty, "account_not_empty", http.StatusConflict, codes.FailedPrecondition},
+	{banking.ErrInvalidTransition, "invalid_transition", http.StatusConflict, codes.FailedPrecondition},
+	{usecases.ErrTransactionConflict, "transaction_conflict", http.StatusConflict, codes.Aborted},
+	{usecases.ErrConcurrentModification, "concurrent_modification", http.StatusConflict, codes.Aborted},
+	{usecases.ErrLockNotAcquired, "lock_not_acquired", http.StatusConflict, codes.Aborted},
+	{usecases.ErrLockLost, "lock_lost", http.StatusConflict, codes.Aborted},
+	{usecases.ErrDeliveryNotDead, "delivery_not_dead", http.StatusConflict, codes.FailedPrecondition},
+
+	{banking.ErrInsufficientFunds, "insufficient_funds", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{banking.ErrCurrencyMismatch, "currency_mismatch", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{banking.ErrInvalidSpread, "invalid_spread", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{usecases.ErrRateUnavailable, "rate_unavailable", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+	{usecases.ErrIdempotencyKeyReused, "idempotency_key_reused", http.StatusUnprocessableEntity, codes.InvalidArgument},
+
+	{context.Canceled, "canceled", StatusClientClosedRequest, codes.Canceled},
+	{context.DeadlineExceeded, "deadline_exceeded", http.StatusGatewayTimeout, codes.DeadlineExceeded},
+}
+
+// ToError classifies err. Anything unknown is an internal error whose
+// message is not shown to clients.
+func ToError(err error) Error {
+	for _, m := range mappings {
+		if errors.Is(err, m.err) {
+			return Error{Code: m.code, Message: m.err.Error(), HTTPStatus: m.httpStatus, GRPCCode: m.grpcCode}
+		}
+	}
+
+	return Error{
+		Code:       "internal",
+		Message:    "internal error",
+		HTTPStatus: http.StatusInternalServerError,
+		GRPCCode:   codes.Internal,
+	}
+}
+
+// ToStatus turns err into a gRPC status error carrying its code as an ErrorInfo reason
+func ToStatus(err error) error {
+	apiErr := ToError(err)
+	st := status.New(apiErr.GRPCCode, apiErr.Message)
+	if detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{Reason: apiErr.Code, Domain: ErrorDomain}); detailsErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,66 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToError(t *testing.T) {
+	tests := []struct {
+		err            error
+		wantCode       string
+		wantHTTPStatus int
+		wantGRPCCode   codes.Code
+	}{
+		{banking.ErrInvalidAmount, "invalid_amount", http.StatusBadRequest, codes.InvalidArgument},
+		{banking.ErrSameAccount, "same_account", http.StatusBadRequest, codes.InvalidArgument},
+		{banking.ErrAccountNotFound, "account_not_found", http.StatusNotFound, codes.NotFound},
+		{banking.ErrAccountFrozen, "account_frozen", http.StatusConflict, codes.FailedPrecondition},
+		{usecases.ErrWebhookNotFound, "webhook_not_found", http.StatusNotFound, codes.NotFound},
+		{fmt.Errorf("%w: deadlock", usecases.ErrTransactionConflict), "transaction_conflict", http.StatusConflict, codes.Aborted},
+		{fmt.Errorf("%w: account acc1", usecases.ErrConcurrentModification), "concurrent_modification", http.StatusConflict, codes.Aborted},
+		{banking.ErrInsufficientFunds, "insufficient_funds", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{fmt.Errorf("wrapped: %w", banking.ErrCurrencyMismatch), "currency_mismatch", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{fmt.Errorf("%w: sql: transaction has already been committed or rolled back", context.Canceled), "canceled", api.StatusClientClosedRequest, codes.Canceled},
+		{context.DeadlineExceeded, "deadline_exceeded", http.StatusGatewayTimeout, codes.DeadlineExceeded},
+		{errors.New("connection refused"), "internal", http.StatusInternalServerError, codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			got := api.ToError(tt.err)
+
+			if got.Code != tt.wantCode || got.HTTPStatus != tt.wantHTTPStatus || got.GRPCCode != tt.wantGRPCCode {
+				t.Errorf("ToError() = %+v, want %v %v %v", got, tt.wantCode, tt.wantHTTPStatus, tt.wantGRPCCode)
+			}
+		})
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	st := status.Convert(api.ToStatus(banking.ErrInsufficientFunds))
+
+	if st.Code() != codes.FailedPrecondition {
+		t.Errorf("Code = %v, want %v", st.Code(), codes.FailedPrecondition)
+	}
+
+	details := st.Details()
+	if len(details) != 1 {
+		t.Fatalf("Details = %v, want one ErrorInfo", details)
+	}
+
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	if !ok || info.Reason != "insufficient_funds" || info.Domain != api.ErrorDomain {
+		t.Errorf("ErrorInfo = %v, want reason insufficient_funds", details[0])
+	}
+}
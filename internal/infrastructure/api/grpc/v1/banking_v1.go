@@ -2,36 +2,249 @@ package v1
 
 import (
 	"context"
+	"time"
 
 	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
 )
 
 // BankingServer implements the BankingServiceServer interface
 type BankingServer struct {
-	transferMoneyUseCase *usecases.TransferMoneyUseCase
+	UnimplementedBankingServiceServer
+	transferMoneyUseCase   *usecases.TransferMoneyUseCase
+	getTransferUseCase     *usecases.GetTransferUseCase
+	openAccountUseCase     *usecases.OpenAccountUseCase
+	freezeAccountUseCase   *usecases.FreezeAccountUseCase
+	unfreezeAccountUseCase *usecases.UnfreezeAccountUseCase
+	closeAccountUseCase    *usecases.CloseAccountUseCase
+	getAccountUseCase      *usecases.GetAccountUseCase
+	listAccountsUseCase    *usecases.ListAccountsUseCase
+	getStatementUseCase    *usecases.GetStatementUseCase
+	getBalanceAtUseCase    *usecases.GetBalanceAtUseCase
+	depositUseCase         *usecases.DepositUseCase
+	withdrawUseCase        *usecases.WithdrawUseCase
 }
 
 // NewBankingServer creates a new BankingServer instance
-func NewBankingServer(transferMoneyUseCase *usecases.TransferMoneyUseCase) *BankingServer {
+func NewBankingServer(
+	transferMoneyUseCase *usecases.TransferMoneyUseCase,
+	getTransferUseCase *usecases.GetTransferUseCase,
+	openAccountUseCase *usecases.OpenAccountUseCase,
+	freezeAccountUseCase *usecases.FreezeAccountUseCase,
+	unfreezeAccountUseCase *usecases.UnfreezeAccountUseCase,
+	closeAccountUseCase *usecases.CloseAccountUseCase,
+	getAccountUseCase *usecases.GetAccountUseCase,
+	listAccountsUseCase *usecases.ListAccountsUseCase,
+	getStatementUseCase *usecases.GetStatementUseCase,
+	getBalanceAtUseCase *usecases.GetBalanceAtUseCase,
+	depositUseCase *usecases.DepositUseCase,
+	withdrawUseCase *usecases.WithdrawUseCase,
+) *BankingServer {
 	return &BankingServer{
-		transferMoneyUseCase: transferMoneyUseCase,
+		transferMoneyUseCase:   transferMoneyUseCase,
+		getTransferUseCase:     getTransferUseCase,
+		openAccountUseCase:     openAccountUseCase,
+		freezeAccountUseCase:   freezeAccountUseCase,
+		unfreezeAccountUseCase: unfreezeAccountUseCase,
+		closeAccountUseCase:    closeAccountUseCase,
+		getAccountUseCase:      getAccountUseCase,
+		listAccountsUseCase:    listAccountsUseCase,
+		getStatementUseCase:    getStatementUseCase,
+		getBalanceAtUseCase:    getBalanceAtUseCase,
+		depositUseCase:         depositUseCase,
+		withdrawUseCase:        withdrawUseCase,
 	}
 }
 
 // TransferMoney handles money transfers between accounts
 func (s *BankingServer) TransferMoney(ctx context.Context, req *TransferMoneyRequest) (*TransferMoneyResponse, error) {
-	err := s.transferMoneyUseCase.Execute(
+	if !banking.ValidCurrency(req.GetCurrency()) {
+		return nil, api.ToStatus(banking.ErrInvalidCurrency)
+	}
+
+	transfer, err := s.transferMoneyUseCase.ExecuteIdempotent(
+		ctx,
+		req.GetIdempotencyKey(),
 		req.GetFromAccountId(),
 		req.GetToAccountId(),
-		int(req.GetAmount()),
+		banking.NewMoney(int(req.GetAmount()), req.GetCurrency()),
 	)
 	if err != nil {
-		return nil, err
+		return nil, api.ToStatus(err)
 	}
 
-	// For now, return a simple success response
 	return &TransferMoneyResponse{
-		Success: true,
-		Message: "Transfer completed successfully",
+		Success:    true,
+		Message:    "Transfer completed successfully",
+		TransferId: transfer.ID,
+	}, nil
+}
+
+// GetTransfer looks up a transfer by its ID
+func (s *BankingServer) GetTransfer(ctx context.Context, req *GetTransferRequest) (*Transfer, error) {
+	transfer, err := s.getTransferUseCase.Execute(ctx, req.GetId())
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+
+	return &Transfer{
+		Id:            transfer.ID,
+		FromAccountId: transfer.From,
+		ToAccountId:   transfer.To,
+		Amount:        int64(transfer.Amount.Amount),
+		Currency:      transfer.Amount.Currency,
+		ConversionId:  transfer.ConversionID,
+		Status:        string(transfer.Status),
+		FailureReason: transfer.FailureReason,
+		CreatedAtUnix: transfer.CreatedAt.Unix(),
+		UpdatedAtUnix: transfer.UpdatedAt.Unix(),
 	}, nil
 }
+
+// OpenAccount opens an empty account
+func (s *BankingServer) OpenAccount(ctx context.Context, req *OpenAccountRequest) (*Account, error) {
+	account, err := s.openAccountUseCase.Execute(ctx, req.GetOwner(), req.GetCurrency(), req.GetActor())
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+// FreezeAccount freezes an account
+func (s *BankingServer) FreezeAccount(ctx context.Context, req *ChangeAccountStatusRequest) (*Account, error) {
+	return changeAccountStatus(ctx, req, s.freezeAccountUseCase.Execute)
+}
+
+// UnfreezeAccount unfreezes an account
+func (s *BankingServer) UnfreezeAccount(ctx context.Context, req *ChangeAccountStatusRequest) (*Account, error) {
+	return changeAccountStatus(ctx, req, s.unfreezeAccountUseCase.Execute)
+}
+
+// CloseAccount closes an account
+func (s *BankingServer) CloseAccount(ctx context.Context, req *ChangeAccountStatusRequest) (*Account, error) {
+	return changeAccountStatus(ctx, req, s.closeAccountUseCase.Execute)
+}
+
+// GetAccount looks up an account by its ID
+func (s *BankingServer) GetAccount(ctx context.Context, req *GetAccountRequest) (*Account, error) {
+	account, err := s.getAccountUseCase.Execute(ctx, req.GetId())
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+// ListAccounts returns a page of accounts
+func (s *BankingServer) ListAccounts(ctx context.Context, req *ListAccountsRequest) (*ListAccountsResponse, error) {
+	accounts, nextPageToken, err := s.listAccountsUseCase.Execute(ctx, req.GetPageToken(), int(req.GetPageSize()))
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+
+	res := &ListAccountsResponse{NextPageToken: nextPageToken}
+	for _, account := range accounts {
+		res.Accounts = append(res.Accounts, toAccount(account))
+	}
+	return res, nil
+}
+
+// GetStatement returns the movements on an account within a date range
+func (s *BankingServer) GetStatement(ctx context.Context, req *GetStatementRequest) (*Statement, error) {
+	statement, err := s.getStatementUseCase.Execute(
+		ctx,
+		req.GetAccountId(),
+		time.Unix(req.GetFromUnix(), 0).UTC(),
+		time.Unix(req.GetToUnix(), 0).UTC(),
+	)
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+
+	res := &Statement{
+		AccountId:      statement.AccountID,
+		Currency:       statement.Currency,
+		FromUnix:       statement.From.Unix(),
+		ToUnix:         statement.To.Unix(),
+		OpeningBalance: int64(statement.OpeningBalance),
+		ClosingBalance: int64(statement.ClosingBalance),
+	}
+	for _, entry := range statement.Entries {
+		res.Entries = append(res.Entries, &StatementEntry{
+			JournalId:     entry.JournalID,
+			Amount:        int64(entry.Amount),
+			Currency:      entry.Currency,
+			CreatedAtUnix: entry.CreatedAt.Unix(),
+		})
+	}
+	return res, nil
+}
+
+// GetBalanceAt returns the balance an account had at a point in time, which
+// must be after the Unix epoch
+func (s *BankingServer) GetBalanceAt(ctx context.Context, req *GetBalanceAtRequest) (*Balance, error) {
+	if req.GetAtUnix() <= 0 {
+		return nil, api.ToStatus(api.ErrMalformedRequest)
+	}
+
+	at := time.Unix(req.GetAtUnix(), 0).UTC()
+	balance, err := s.getBalanceAtUseCase.Execute(ctx, req.GetAccountId(), at)
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+
+	return &Balance{
+		AccountId: req.GetAccountId(),
+		AtUnix:    at.Unix(),
+		Balance:   int64(balance.Amount),
+		Currency:  balance.Currency,
+	}, nil
+}
+
+// Deposit adds money to an account
+func (s *BankingServer) Deposit(ctx context.Context, req *MoveMoneyRequest) (*Account, error) {
+	return moveMoney(ctx, req, s.depositUseCase.Execute)
+}
+
+// Withdraw takes money from an account
+func (s *BankingServer) Withdraw(ctx context.Context, req *MoveMoneyRequest) (*Account, error) {
+	return moveMoney(ctx, req, s.withdrawUseCase.Execute)
+}
+
+func moveMoney(
+	ctx context.Context,
+	req *MoveMoneyRequest,
+	move func(ctx context.Context, id string, amount banking.Money) (*banking.Account, error),
+) (*Account, error) {
+	if !banking.ValidCurrency(req.GetCurrency()) {
+		return nil, api.ToStatus(banking.ErrInvalidCurrency)
+	}
+
+	account, err := move(ctx, req.GetAccountId(), banking.NewMoney(int(req.GetAmount()), req.GetCurrency()))
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+func changeAccountStatus(
+	ctx context.Context,
+	req *ChangeAccountStatusRequest,
+	change func(ctx context.Context, id, reason, actor string) (*banking.Account, error),
+) (*Account, error) {
+	account, err := change(ctx, req.GetAccountId(), req.GetReason(), req.GetActor())
+	if err != nil {
+		return nil, api.ToStatus(err)
+	}
+	return toAccount(account), nil
+}
+
+func toAccount(account *banking.Account) *Account {
+	return &Account{
+		Id:       account.ID,
+		Owner:    account.Owner,
+		Balance:  int64(account.Balance),
+		Currency: account.Currency,
+		Status:   string(account.Status),
+	}
+}
@@ -19,7 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BankingService_TransferMoney_FullMethodName = "/banking.v1.BankingService/TransferMoney"
+	BankingService_TransferMoney_FullMethodName   = "/banking.v1.BankingService/TransferMoney"
+	BankingService_GetTransfer_FullMethodName     = "/banking.v1.BankingService/GetTransfer"
+	BankingService_OpenAccount_FullMethodName     = "/banking.v1.BankingService/OpenAccount"
+	BankingService_FreezeAccount_FullMethodName   = "/banking.v1.BankingService/FreezeAccount"
+	BankingService_UnfreezeAccount_FullMethodName = "/banking.v1.BankingService/UnfreezeAccount"
+	BankingService_CloseAccount_FullMethodName    = "/banking.v1.BankingService/CloseAccount"
+	BankingService_GetAccount_FullMethodName      = "/banking.v1.BankingService/GetAccount"
+	BankingService_ListAccounts_FullMethodName    = "/banking.v1.BankingService/ListAccounts"
+	BankingService_GetStatement_FullMethodName    = "/banking.v1.BankingService/GetStatement"
+	BankingService_GetBalanceAt_FullMethodName    = "/banking.v1.BankingService/GetBalanceAt"
+	BankingService_Deposit_FullMethodName         = "/banking.v1.BankingService/Deposit"
+	BankingService_Withdraw_FullMethodName        = "/banking.v1.BankingService/Withdraw"
 )
 
 // BankingServiceClient is the client API for BankingService service.
@@ -30,6 +41,28 @@ const (
 type BankingServiceClient interface {
 	// TransferMoney transfers money between two accounts
 	TransferMoney(ctx context.Context, in *TransferMoneyRequest, opts ...grpc.CallOption) (*TransferMoneyResponse, error)
+	// GetTransfer looks up a transfer by the ID returned when it was requested
+	GetTransfer(ctx context.Context, in *GetTransferRequest, opts ...grpc.CallOption) (*Transfer, error)
+	// OpenAccount opens an empty account for an owner in a currency
+	OpenAccount(ctx context.Context, in *OpenAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	// FreezeAccount stops money from moving in or out of an account
+	FreezeAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error)
+	// UnfreezeAccount lets money move again on a frozen account
+	UnfreezeAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error)
+	// CloseAccount closes an account whose balance is zero
+	CloseAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error)
+	// GetAccount looks up an account by its ID
+	GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	// ListAccounts returns a page of accounts ordered by ID
+	ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error)
+	// GetStatement returns the ledger entries of an account within a date range
+	GetStatement(ctx context.Context, in *GetStatementRequest, opts ...grpc.CallOption) (*Statement, error)
+	// GetBalanceAt returns the balance an account had at a point in time
+	GetBalanceAt(ctx context.Context, in *GetBalanceAtRequest, opts ...grpc.CallOption) (*Balance, error)
+	// Deposit adds money coming from outside the bank to an account
+	Deposit(ctx context.Context, in *MoveMoneyRequest, opts ...grpc.CallOption) (*Account, error)
+	// Withdraw takes money out of the bank from an account
+	Withdraw(ctx context.Context, in *MoveMoneyRequest, opts ...grpc.CallOption) (*Account, error)
 }
 
 type bankingServiceClient struct {
@@ -50,6 +83,116 @@ func (c *bankingServiceClient) TransferMoney(ctx context.Context, in *TransferMo
 	return out, nil
 }
 
+func (c *bankingServiceClient) GetTransfer(ctx context.Context, in *GetTransferRequest, opts ...grpc.CallOption) (*Transfer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Transfer)
+	err := c.cc.Invoke(ctx, BankingService_GetTransfer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) OpenAccount(ctx context.Context, in *OpenAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_OpenAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) FreezeAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_FreezeAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) UnfreezeAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_UnfreezeAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) CloseAccount(ctx context.Context, in *ChangeAccountStatusRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_CloseAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_GetAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAccountsResponse)
+	err := c.cc.Invoke(ctx, BankingService_ListAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) GetStatement(ctx context.Context, in *GetStatementRequest, opts ...grpc.CallOption) (*Statement, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Statement)
+	err := c.cc.Invoke(ctx, BankingService_GetStatement_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) GetBalanceAt(ctx context.Context, in *GetBalanceAtRequest, opts ...grpc.CallOption) (*Balance, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, BankingService_GetBalanceAt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) Deposit(ctx context.Context, in *MoveMoneyRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_Deposit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bankingServiceClient) Withdraw(ctx context.Context, in *MoveMoneyRequest, opts ...grpc.CallOption) (*Account, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Account)
+	err := c.cc.Invoke(ctx, BankingService_Withdraw_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BankingServiceServer is the server API for BankingService service.
 // All implementations must embed UnimplementedBankingServiceServer
 // for forward compatibility.
@@ -58,6 +201,28 @@ func (c *bankingServiceClient) TransferMoney(ctx context.Context, in *TransferMo
 type BankingServiceServer interface {
 	// TransferMoney transfers money between two accounts
 	TransferMoney(context.Context, *TransferMoneyRequest) (*TransferMoneyResponse, error)
+	// GetTransfer looks up a transfer by the ID returned when it was requested
+	GetTransfer(context.Context, *GetTransferRequest) (*Transfer, error)
+	// OpenAccount opens an empty account for an owner in a currency
+	OpenAccount(context.Context, *OpenAccountRequest) (*Account, error)
+	// FreezeAccount stops money from moving in or out of an account
+	FreezeAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error)
+	// UnfreezeAccount lets money move again on a frozen account
+	UnfreezeAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error)
+	// CloseAccount closes an account whose balance is zero
+	CloseAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error)
+	// GetAccount looks up an account by its ID
+	GetAccount(context.Context, *GetAccountRequest) (*Account, error)
+	// ListAccounts returns a page of accounts ordered by ID
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
+	// GetStatement returns the ledger entries of an account within a date range
+	GetStatement(context.Context, *GetStatementRequest) (*Statement, error)
+	// GetBalanceAt returns the balance an account had at a point in time
+	GetBalanceAt(context.Context, *GetBalanceAtRequest) (*Balance, error)
+	// Deposit adds money coming from outside the bank to an account
+	Deposit(context.Context, *MoveMoneyRequest) (*Account, error)
+	// Withdraw takes money out of the bank from an account
+	Withdraw(context.Context, *MoveMoneyRequest) (*Account, error)
 	mustEmbedUnimplementedBankingServiceServer()
 }
 
@@ -71,6 +236,39 @@ type UnimplementedBankingServiceServer struct{}
 func (UnimplementedBankingServiceServer) TransferMoney(context.Context, *TransferMoneyRequest) (*TransferMoneyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method TransferMoney not implemented")
 }
+func (UnimplementedBankingServiceServer) GetTransfer(context.Context, *GetTransferRequest) (*Transfer, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransfer not implemented")
+}
+func (UnimplementedBankingServiceServer) OpenAccount(context.Context, *OpenAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method OpenAccount not implemented")
+}
+func (UnimplementedBankingServiceServer) FreezeAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FreezeAccount not implemented")
+}
+func (UnimplementedBankingServiceServer) UnfreezeAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnfreezeAccount not implemented")
+}
+func (UnimplementedBankingServiceServer) CloseAccount(context.Context, *ChangeAccountStatusRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseAccount not implemented")
+}
+func (UnimplementedBankingServiceServer) GetAccount(context.Context, *GetAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccount not implemented")
+}
+func (UnimplementedBankingServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
+func (UnimplementedBankingServiceServer) GetStatement(context.Context, *GetStatementRequest) (*Statement, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatement not implemented")
+}
+func (UnimplementedBankingServiceServer) GetBalanceAt(context.Context, *GetBalanceAtRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalanceAt not implemented")
+}
+func (UnimplementedBankingServiceServer) Deposit(context.Context, *MoveMoneyRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (UnimplementedBankingServiceServer) Withdraw(context.Context, *MoveMoneyRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
 func (UnimplementedBankingServiceServer) mustEmbedUnimplementedBankingServiceServer() {}
 func (UnimplementedBankingServiceServer) testEmbeddedByValue()                        {}
 
@@ -110,6 +308,204 @@ func _BankingService_TransferMoney_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BankingService_GetTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).GetTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_GetTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).GetTransfer(ctx, req.(*GetTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_OpenAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OpenAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).OpenAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_OpenAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).OpenAccount(ctx, req.(*OpenAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_FreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).FreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_FreezeAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).FreezeAccount(ctx, req.(*ChangeAccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_UnfreezeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).UnfreezeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_UnfreezeAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).UnfreezeAccount(ctx, req.(*ChangeAccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_CloseAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeAccountStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).CloseAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_CloseAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).CloseAccount(ctx, req.(*ChangeAccountStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_GetAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).GetAccount(ctx, req.(*GetAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_ListAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_GetStatement_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatementRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).GetStatement(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_GetStatement_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).GetStatement(ctx, req.(*GetStatementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_GetBalanceAt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceAtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).GetBalanceAt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_GetBalanceAt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).GetBalanceAt(ctx, req.(*GetBalanceAtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveMoneyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_Deposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).Deposit(ctx, req.(*MoveMoneyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BankingService_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveMoneyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BankingServiceServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BankingService_Withdraw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BankingServiceServer).Withdraw(ctx, req.(*MoveMoneyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BankingService_ServiceDesc is the grpc.ServiceDesc for BankingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -121,6 +517,50 @@ var BankingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "TransferMoney",
 			Handler:    _BankingService_TransferMoney_Handler,
 		},
+		{
+			MethodName: "GetTransfer",
+			Handler:    _BankingService_GetTransfer_Handler,
+		},
+		{
+			MethodName: "OpenAccount",
+			Handler:    _BankingService_OpenAccount_Handler,
+		},
+		{
+			MethodName: "FreezeAccount",
+			Handler:    _BankingService_FreezeAccount_Handler,
+		},
+		{
+			MethodName: "UnfreezeAccount",
+			Handler:    _BankingService_UnfreezeAccount_Handler,
+		},
+		{
+			MethodName: "CloseAccount",
+			Handler:    _BankingService_CloseAccount_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _BankingService_GetAccount_Handler,
+		},
+		{
+			MethodName: "ListAccounts",
+			Handler:    _BankingService_ListAccounts_Handler,
+		},
+		{
+			MethodName: "GetStatement",
+			Handler:    _BankingService_GetStatement_Handler,
+		},
+		{
+			MethodName: "GetBalanceAt",
+			Handler:    _BankingService_GetBalanceAt_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _BankingService_Deposit_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _BankingService_Withdraw_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "internal/infrastructure/api/grpc/banking_v1.proto",
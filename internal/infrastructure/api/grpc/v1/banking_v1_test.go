@@ -0,0 +1,216 @@
+package v1_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
+	v1 "github.com/ppicom/newtonian/internal/infrastructure/api/grpc/v1"
+	"github.com/ppicom/newtonian/internal/infrastructure/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestClient serves the banking service on an in-memory store and
+// connects to it through an in-process listener
+func newTestClient(t *testing.T) v1.BankingServiceClient {
+	t.Helper()
+	store := memory.NewStore()
+	unitOfWork := memory.NewUnitOfWork(store)
+	accounts := memory.NewAccountRepository(store)
+	server := grpc.NewServer(grpc.UnaryInterceptor(v1.TimeoutInterceptor(time.Second)))
+	v1.RegisterBankingServiceServer(server, v1.NewBankingServer(
+		usecases.NewTransferMoneyUseCase(unitOfWork, nil, nil),
+		usecases.NewGetTransferUseCase(memory.NewTransferRepository(store)),
+		usecases.NewOpenAccountUseCase(unitOfWork),
+		usecases.NewFreezeAccountUseCase(unitOfWork),
+		usecases.NewUnfreezeAccountUseCase(unitOfWork),
+		usecases.NewCloseAccountUseCase(unitOfWork),
+		usecases.NewGetAccountUseCase(accounts),
+		usecases.NewListAccountsUseCase(accounts),
+		usecases.NewGetStatementUseCase(unitOfWork),
+		usecases.NewGetBalanceAtUseCase(unitOfWork, nil),
+		usecases.NewDepositUseCase(unitOfWork),
+		usecases.NewWithdrawUseCase(unitOfWork),
+	))
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return v1.NewBankingServiceClient(conn)
+}
+
+// requireStatus checks the code of a failed call and the reason of its ErrorInfo
+func requireStatus(t *testing.T, wantCode codes.Code, wantReason string, err error) {
+	t.Helper()
+	st := status.Convert(err)
+	require.Equal(t, wantCode, st.Code(), st.Message())
+
+	details := st.Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, wantReason, info.Reason)
+	require.Equal(t, api.ErrorDomain, info.Domain)
+}
+
+// openAccount opens a euro account and deposits balance into it
+func openAccount(t *testing.T, client v1.BankingServiceClient, balance int64) string {
+	t.Helper()
+	account, err := client.OpenAccount(t.Context(), &v1.OpenAccountRequest{Owner: "alice", Currency: "EUR"})
+	require.NoError(t, err)
+
+	if balance > 0 {
+		_, err = client.Deposit(t.Context(), &v1.MoveMoneyRequest{AccountId: account.Id, Amount: balance, Currency: "EUR"})
+		require.NoError(t, err)
+	}
+	return account.Id
+}
+
+func TestBankingServer_DepositAndWithdraw(t *testing.T) {
+	client := newTestClient(t)
+	id := openAccount(t, client, 0)
+
+	account, err := client.Deposit(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 100, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), account.Balance)
+
+	account, err = client.Withdraw(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 30, Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(70), account.Balance)
+
+	tests := []struct {
+		name       string
+		move       func(ctx context.Context, in *v1.MoveMoneyRequest, opts ...grpc.CallOption) (*v1.Account, error)
+		req        *v1.MoveMoneyRequest
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{"negative amount", client.Deposit, &v1.MoveMoneyRequest{AccountId: id, Amount: -10, Currency: "EUR"}, codes.InvalidArgument, "invalid_amount"},
+		{"invalid deposit currency", client.Deposit, &v1.MoveMoneyRequest{AccountId: id, Amount: 10, Currency: "eur"}, codes.InvalidArgument, "invalid_currency"},
+		{"invalid withdrawal currency", client.Withdraw, &v1.MoveMoneyRequest{AccountId: id, Amount: 10}, codes.InvalidArgument, "invalid_currency"},
+		{"other currency", client.Deposit, &v1.MoveMoneyRequest{AccountId: id, Amount: 10, Currency: "USD"}, codes.FailedPrecondition, "currency_mismatch"},
+		{"insufficient funds", client.Withdraw, &v1.MoveMoneyRequest{AccountId: id, Amount: 500, Currency: "EUR"}, codes.FailedPrecondition, "insufficient_funds"},
+		{"unknown account", client.Deposit, &v1.MoveMoneyRequest{AccountId: "unknown", Amount: 10, Currency: "EUR"}, codes.NotFound, "account_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.move(t.Context(), tt.req)
+			requireStatus(t, tt.wantCode, tt.wantReason, err)
+		})
+	}
+
+	account, err = client.GetAccount(t.Context(), &v1.GetAccountRequest{Id: id})
+	require.NoError(t, err)
+	assert.Equal(t, int64(70), account.Balance)
+}
+
+func TestBankingServer_AccountStatus(t *testing.T) {
+	client := newTestClient(t)
+	id := openAccount(t, client, 100)
+
+	_, err := client.FreezeAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Actor: "ops"})
+	requireStatus(t, codes.InvalidArgument, "invalid_reason", err)
+
+	account, err := client.FreezeAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Reason: "fraud check", Actor: "ops"})
+	require.NoError(t, err)
+	assert.Equal(t, "frozen", account.Status)
+
+	_, err = client.Withdraw(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 10, Currency: "EUR"})
+	requireStatus(t, codes.FailedPrecondition, "account_frozen", err)
+
+	account, err = client.UnfreezeAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Reason: "cleared"})
+	require.NoError(t, err)
+	assert.Equal(t, "active", account.Status)
+
+	_, err = client.UnfreezeAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Reason: "cleared"})
+	requireStatus(t, codes.FailedPrecondition, "account_not_frozen", err)
+
+	_, err = client.CloseAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Reason: "customer request"})
+	requireStatus(t, codes.FailedPrecondition, "account_not_empty", err)
+
+	_, err = client.Withdraw(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 100, Currency: "EUR"})
+	require.NoError(t, err)
+	account, err = client.CloseAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: id, Reason: "customer request"})
+	require.NoError(t, err)
+	assert.Equal(t, "closed", account.Status)
+
+	_, err = client.Deposit(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 10, Currency: "EUR"})
+	requireStatus(t, codes.FailedPrecondition, "account_closed", err)
+
+	_, err = client.FreezeAccount(t.Context(), &v1.ChangeAccountStatusRequest{AccountId: "unknown", Reason: "fraud check"})
+	requireStatus(t, codes.NotFound, "account_not_found", err)
+}
+
+func TestBankingServer_Transfers(t *testing.T) {
+	client := newTestClient(t)
+	from := openAccount(t, client, 100)
+	to := openAccount(t, client, 0)
+
+	res, err := client.TransferMoney(t.Context(), &v1.TransferMoneyRequest{FromAccountId: from, ToAccountId: to, Amount: 30, Currency: "EUR"})
+	require.NoError(t, err)
+	transfer, err := client.GetTransfer(t.Context(), &v1.GetTransferRequest{Id: res.TransferId})
+	require.NoError(t, err)
+	assert.Equal(t, "completed", transfer.Status)
+	assert.Equal(t, from, transfer.FromAccountId)
+	assert.Equal(t, int64(30), transfer.Amount)
+
+	_, err = client.TransferMoney(t.Context(), &v1.TransferMoneyRequest{FromAccountId: from, ToAccountId: from, Amount: 30, Currency: "EUR"})
+	requireStatus(t, codes.InvalidArgument, "same_account", err)
+
+	_, err = client.TransferMoney(t.Context(), &v1.TransferMoneyRequest{FromAccountId: from, ToAccountId: to, Amount: 30, Currency: "euro"})
+	requireStatus(t, codes.InvalidArgument, "invalid_currency", err)
+
+	_, err = client.TransferMoney(t.Context(), &v1.TransferMoneyRequest{FromAccountId: from, ToAccountId: to, Amount: 500, Currency: "EUR"})
+	requireStatus(t, codes.FailedPrecondition, "insufficient_funds", err)
+
+	_, err = client.GetTransfer(t.Context(), &v1.GetTransferRequest{Id: "unknown"})
+	requireStatus(t, codes.NotFound, "transfer_not_found", err)
+}
+
+func TestBankingServer_StatementAndBalance(t *testing.T) {
+	client := newTestClient(t)
+	id := openAccount(t, client, 100)
+	_, err := client.Withdraw(t.Context(), &v1.MoveMoneyRequest{AccountId: id, Amount: 30, Currency: "EUR"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	from, to := now.Add(-time.Hour).Unix(), now.Add(time.Hour).Unix()
+	statement, err := client.GetStatement(t.Context(), &v1.GetStatementRequest{AccountId: id, FromUnix: from, ToUnix: to})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), statement.OpeningBalance)
+	assert.Equal(t, int64(70), statement.ClosingBalance)
+	assert.Len(t, statement.Entries, 2)
+
+	_, err = client.GetStatement(t.Context(), &v1.GetStatementRequest{AccountId: id, FromUnix: to, ToUnix: from})
+	requireStatus(t, codes.InvalidArgument, "invalid_date_range", err)
+
+	_, err = client.GetStatement(t.Context(), &v1.GetStatementRequest{AccountId: "unknown", FromUnix: from, ToUnix: to})
+	requireStatus(t, codes.NotFound, "account_not_found", err)
+
+	balance, err := client.GetBalanceAt(t.Context(), &v1.GetBalanceAtRequest{AccountId: id, AtUnix: now.Add(time.Second).Unix()})
+	require.NoError(t, err)
+	assert.Equal(t, int64(70), balance.Balance)
+	assert.Equal(t, "EUR", balance.Currency)
+
+	_, err = client.GetBalanceAt(t.Context(), &v1.GetBalanceAtRequest{AccountId: id})
+	requireStatus(t, codes.InvalidArgument, "malformed_request", err)
+}
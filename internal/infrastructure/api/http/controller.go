@@ -1,20 +1,59 @@
 package http
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
 )
 
 type Controller struct {
-	transferMoneyUseCase *usecases.TransferMoneyUseCase
+	transferMoneyUseCase   *usecases.TransferMoneyUseCase
+	getTransferUseCase     *usecases.GetTransferUseCase
+	openAccountUseCase     *usecases.OpenAccountUseCase
+	freezeAccountUseCase   *usecases.FreezeAccountUseCase
+	unfreezeAccountUseCase *usecases.UnfreezeAccountUseCase
+	closeAccountUseCase    *usecases.CloseAccountUseCase
+	getAccountUseCase      *usecases.GetAccountUseCase
+	listAccountsUseCase    *usecases.ListAccountsUseCase
+	getStatementUseCase    *usecases.GetStatementUseCase
+	getBalanceAtUseCase    *usecases.GetBalanceAtUseCase
+	depositUseCase         *usecases.DepositUseCase
+	withdrawUseCase        *usecases.WithdrawUseCase
 }
 
-func NewController(transferMoneyUseCase *usecases.TransferMoneyUseCase) *Controller {
+func NewController(
+	transferMoneyUseCase *usecases.TransferMoneyUseCase,
+	getTransferUseCase *usecases.GetTransferUseCase,
+	openAccountUseCase *usecases.OpenAccountUseCase,
+	freezeAccountUseCase *usecases.FreezeAccountUseCase,
+	unfreezeAccountUseCase *usecases.UnfreezeAccountUseCase,
+	closeAccountUseCase *usecases.CloseAccountUseCase,
+	getAccountUseCase *usecases.GetAccountUseCase,
+	listAccountsUseCase *usecases.ListAccountsUseCase,
+	getStatementUseCase *usecases.GetStatementUseCase,
+	getBalanceAtUseCase *usecases.GetBalanceAtUseCase,
+	depositUseCase *usecases.DepositUseCase,
+	withdrawUseCase *usecases.WithdrawUseCase,
+) *Controller {
 	return &Controller{
-		transferMoneyUseCase: transferMoneyUseCase,
+		transferMoneyUseCase:   transferMoneyUseCase,
+		getTransferUseCase:     getTransferUseCase,
+		openAccountUseCase:     openAccountUseCase,
+		freezeAccountUseCase:   freezeAccountUseCase,
+		unfreezeAccountUseCase: unfreezeAccountUseCase,
+		closeAccountUseCase:    closeAccountUseCase,
+		getAccountUseCase:      getAccountUseCase,
+		listAccountsUseCase:    listAccountsUseCase,
+		getStatementUseCase:    getStatementUseCase,
+		getBalanceAtUseCase:    getBalanceAtUseCase,
+		depositUseCase:         depositUseCase,
+		withdrawUseCase:        withdrawUseCase,
 	}
 }
 
@@ -22,6 +61,17 @@ func (c *Controller) SetupRoutes(router *Router) {
 	api := router.Engine().Group("/api/v1")
 	{
 		api.POST("/transfer", c.TransferMoney)
+		api.GET("/transfers/:id", c.GetTransfer)
+		api.GET("/accounts", c.ListAccounts)
+		api.GET("/accounts/:id", c.GetAccount)
+		api.GET("/accounts/:id/statement", c.GetStatement)
+		api.GET("/accounts/:id/balance", c.GetBalanceAt)
+		api.POST("/accounts", c.OpenAccount)
+		api.POST("/accounts/:id/freeze", c.FreezeAccount)
+		api.POST("/accounts/:id/unfreeze", c.UnfreezeAccount)
+		api.POST("/accounts/:id/close", c.CloseAccount)
+		api.POST("/accounts/:id/deposits", c.Deposit)
+		api.POST("/accounts/:id/withdrawals", c.Withdraw)
 	}
 }
 
@@ -30,15 +80,212 @@ func (c *Controller) TransferMoney(ctx *gin.Context) {
 	to := ctx.PostForm("to")
 	amount, err := strconv.Atoi(ctx.PostForm("amount"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid amount"})
+		respondError(ctx, banking.ErrInvalidAmount)
 		return
 	}
 
-	err = c.transferMoneyUseCase.Execute(from, to, amount)
+	currency := ctx.PostForm("currency")
+	if !banking.ValidCurrency(currency) {
+		respondError(ctx, banking.ErrInvalidCurrency)
+		return
+	}
+
+	idempotencyKey := ctx.GetHeader("Idempotency-Key")
+	transfer, err := c.transferMoneyUseCase.ExecuteIdempotent(ctx.Request.Context(), idempotencyKey, from, to, banking.NewMoney(amount, currency))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Transfer successful", "transfer_id": transfer.ID})
+}
+
+func (c *Controller) GetTransfer(ctx *gin.Context) {
+	transfer, err := c.getTransferUseCase.Execute(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transferJSON(transfer))
+}
+
+func transferJSON(transfer *banking.MoneyTransfer) gin.H {
+	return gin.H{
+		"id":             transfer.ID,
+		"from":           transfer.From,
+		"to":             transfer.To,
+		"amount":         transfer.Amount.Amount,
+		"currency":       transfer.Amount.Currency,
+		"conversion_id":  transfer.ConversionID,
+		"status":         transfer.Status,
+		"failure_reason": transfer.FailureReason,
+		"created_at":     transfer.CreatedAt,
+		"updated_at":     transfer.UpdatedAt,
+	}
+}
+
+func (c *Controller) Deposit(ctx *gin.Context) {
+	c.moveMoney(ctx, c.depositUseCase.Execute)
+}
+
+func (c *Controller) Withdraw(ctx *gin.Context) {
+	c.moveMoney(ctx, c.withdrawUseCase.Execute)
+}
+
+func (c *Controller) moveMoney(ctx *gin.Context, move func(ctx context.Context, id string, amount banking.Money) (*banking.Account, error)) {
+	amount, err := strconv.Atoi(ctx.PostForm("amount"))
+	if err != nil {
+		respondError(ctx, banking.ErrInvalidAmount)
+		return
+	}
+
+	currency := ctx.PostForm("currency")
+	if !banking.ValidCurrency(currency) {
+		respondError(ctx, banking.ErrInvalidCurrency)
+		return
+	}
+
+	account, err := move(ctx.Request.Context(), ctx.Param("id"), banking.NewMoney(amount, currency))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountJSON(account))
+}
+
+func (c *Controller) GetAccount(ctx *gin.Context) {
+	account, err := c.getAccountUseCase.Execute(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountJSON(account))
+}
+
+func (c *Controller) ListAccounts(ctx *gin.Context) {
+	pageSize := 0
+	if value := ctx.Query("page_size"); value != "" {
+		var err error
+		if pageSize, err = strconv.Atoi(value); err != nil {
+			respondError(ctx, api.ErrMalformedRequest)
+			return
+		}
+	}
+
+	accounts, nextPageToken, err := c.listAccountsUseCase.Execute(ctx.Request.Context(), ctx.Query("page_token"), pageSize)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(accounts))
+	for _, account := range accounts {
+		items = append(items, accountJSON(account))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"accounts": items, "next_page_token": nextPageToken})
+}
+
+func (c *Controller) GetStatement(ctx *gin.Context) {
+	from, err := time.Parse(time.RFC3339, ctx.Query("from"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, api.ErrMalformedRequest)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "Transfer successful"})
+	to, err := time.Parse(time.RFC3339, ctx.Query("to"))
+	if err != nil {
+		respondError(ctx, api.ErrMalformedRequest)
+		return
+	}
+
+	statement, err := c.getStatementUseCase.Execute(ctx.Request.Context(), ctx.Param("id"), from, to)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	entries := make([]gin.H, 0, len(statement.Entries))
+	for _, entry := range statement.Entries {
+		entries = append(entries, gin.H{
+			"journal_id": entry.JournalID,
+			"amount":     entry.Amount,
+			"currency":   entry.Currency,
+			"created_at": entry.CreatedAt,
+		})
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"account_id":      statement.AccountID,
+		"currency":        statement.Currency,
+		"from":            statement.From,
+		"to":              statement.To,
+		"opening_balance": statement.OpeningBalance,
+		"closing_balance": statement.ClosingBalance,
+		"entries":         entries,
+	})
+}
+
+// GetBalanceAt returns the balance of the account at the RFC 3339 time at
+func (c *Controller) GetBalanceAt(ctx *gin.Context) {
+	at, err := time.Parse(time.RFC3339, ctx.Query("at"))
+	if err != nil {
+		respondError(ctx, api.ErrMalformedRequest)
+		return
+	}
+
+	balance, err := c.getBalanceAtUseCase.Execute(ctx.Request.Context(), ctx.Param("id"), at)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"account_id": ctx.Param("id"),
+		"at":         at,
+		"balance":    balance.Amount,
+		"currency":   balance.Currency,
+	})
+}
+
+func (c *Controller) OpenAccount(ctx *gin.Context) {
+	account, err := c.openAccountUseCase.Execute(ctx.Request.Context(), ctx.PostForm("owner"), ctx.PostForm("currency"), ctx.PostForm("actor"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, accountJSON(account))
+}
+
+func (c *Controller) FreezeAccount(ctx *gin.Context) {
+	c.changeAccountStatus(ctx, c.freezeAccountUseCase.Execute)
+}
+
+func (c *Controller) UnfreezeAccount(ctx *gin.Context) {
+	c.changeAccountStatus(ctx, c.unfreezeAccountUseCase.Execute)
+}
+
+func (c *Controller) CloseAccount(ctx *gin.Context) {
+	c.changeAccountStatus(ctx, c.closeAccountUseCase.Execute)
+}
+
+func (c *Controller) changeAccountStatus(ctx *gin.Context, change func(ctx context.Context, id, reason, actor string) (*banking.Account, error)) {
+	account, err := change(ctx.Request.Context(), ctx.Param("id"), ctx.PostForm("reason"), ctx.PostForm("actor"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accountJSON(account))
+}
+
+func accountJSON(account *banking.Account) gin.H {
+	return gin.H{
+		"id":       account.ID,
+		"owner":    account.Owner,
+		"balance":  account.Balance,
+		"currency": account.Currency,
+		"status":   account.Status,
+	}
 }
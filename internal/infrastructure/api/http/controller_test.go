@@ -0,0 +1,246 @@
+package http_test
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/infrastructure/api/http"
+	"github.com/ppicom/newtonian/internal/infrastructure/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRouter serves the banking routes on an in-memory store
+func newTestRouter(t *testing.T) *http.Router {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := memory.NewStore()
+	unitOfWork := memory.NewUnitOfWork(store)
+	accounts := memory.NewAccountRepository(store)
+	router := http.NewRouter(time.Second)
+	controller := http.NewController(
+		usecases.NewTransferMoneyUseCase(unitOfWork, nil, nil),
+		usecases.NewGetTransferUseCase(memory.NewTransferRepository(store)),
+		usecases.NewOpenAccountUseCase(unitOfWork),
+		usecases.NewFreezeAccountUseCase(unitOfWork),
+		usecases.NewUnfreezeAccountUseCase(unitOfWork),
+		usecases.NewCloseAccountUseCase(unitOfWork),
+		usecases.NewGetAccountUseCase(accounts),
+		usecases.NewListAccountsUseCase(accounts),
+		usecases.NewGetStatementUseCase(unitOfWork),
+		usecases.NewGetBalanceAtUseCase(unitOfWork, nil),
+		usecases.NewDepositUseCase(unitOfWork),
+		usecases.NewWithdrawUseCase(unitOfWork),
+	)
+	controller.SetupRoutes(router)
+	return router
+}
+
+// send posts form, or sends it as the query of other methods, and decodes
+// the JSON response, if any
+func send(t *testing.T, router *http.Router, method, path string, form url.Values) (int, map[string]any) {
+	t.Helper()
+	var req *nethttp.Request
+	if method == nethttp.MethodPost {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path+"?"+form.Encode(), nil)
+	}
+	rec := httptest.NewRecorder()
+	router.Engine().ServeHTTP(rec, req)
+
+	var body map[string]any
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	}
+	return rec.Code, body
+}
+
+// requireError checks the status and code of an error response
+func requireError(t *testing.T, wantStatus int, wantCode string, status int, body map[string]any) {
+	t.Helper()
+	require.Equal(t, wantStatus, status)
+	require.Equal(t, wantCode, body["code"])
+}
+
+// openAccount opens a euro account through the API and deposits balance into it
+func openAccount(t *testing.T, router *http.Router, balance int) string {
+	t.Helper()
+	status, body := send(t, router, nethttp.MethodPost, "/api/v1/accounts", url.Values{"owner": {"alice"}, "currency": {"EUR"}})
+	require.Equal(t, nethttp.StatusCreated, status)
+	id := body["id"].(string)
+
+	if balance > 0 {
+		status, _ = send(t, router, nethttp.MethodPost, "/api/v1/accounts/"+id+"/deposits", money(balance, "EUR"))
+		require.Equal(t, nethttp.StatusOK, status)
+	}
+	return id
+}
+
+func money(amount int, currency string) url.Values {
+	return url.Values{"amount": {strconv.Itoa(amount)}, "currency": {currency}}
+}
+
+func TestController_DepositAndWithdraw(t *testing.T) {
+	router := newTestRouter(t)
+	id := openAccount(t, router, 0)
+
+	status, body := send(t, router, nethttp.MethodPost, "/api/v1/accounts/"+id+"/deposits", money(100, "EUR"))
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.EqualValues(t, 100, body["balance"])
+
+	status, body = send(t, router, nethttp.MethodPost, "/api/v1/accounts/"+id+"/withdrawals", money(30, "EUR"))
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.EqualValues(t, 70, body["balance"])
+
+	tests := []struct {
+		name       string
+		path       string
+		form       url.Values
+		wantStatus int
+		wantCode   string
+	}{
+		{"malformed amount", "/deposits", url.Values{"amount": {"ten"}, "currency": {"EUR"}}, nethttp.StatusBadRequest, "invalid_amount"},
+		{"negative amount", "/deposits", money(-10, "EUR"), nethttp.StatusBadRequest, "invalid_amount"},
+		{"invalid currency", "/deposits", money(10, "eur"), nethttp.StatusBadRequest, "invalid_currency"},
+		{"other currency", "/deposits", money(10, "USD"), nethttp.StatusUnprocessableEntity, "currency_mismatch"},
+		{"insufficient funds", "/withdrawals", money(500, "EUR"), nethttp.StatusUnprocessableEntity, "insufficient_funds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := send(t, router, nethttp.MethodPost, "/api/v1/accounts/"+id+tt.path, tt.form)
+			requireError(t, tt.wantStatus, tt.wantCode, status, body)
+		})
+	}
+
+	status, body = send(t, router, nethttp.MethodPost, "/api/v1/accounts/unknown/deposits", money(10, "EUR"))
+	requireError(t, nethttp.StatusNotFound, "account_not_found", status, body)
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id, nil)
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.EqualValues(t, 70, body["balance"])
+}
+
+func TestController_AccountStatus(t *testing.T) {
+	router := newTestRouter(t)
+	id := openAccount(t, router, 100)
+	path := "/api/v1/accounts/" + id
+
+	status, body := send(t, router, nethttp.MethodPost, path+"/freeze", url.Values{"actor": {"ops"}})
+	requireError(t, nethttp.StatusBadRequest, "invalid_reason", status, body)
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/freeze", url.Values{"reason": {"fraud check"}, "actor": {"ops"}})
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.Equal(t, "frozen", body["status"])
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/withdrawals", money(10, "EUR"))
+	requireError(t, nethttp.StatusConflict, "account_frozen", status, body)
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/unfreeze", url.Values{"reason": {"cleared"}})
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.Equal(t, "active", body["status"])
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/unfreeze", url.Values{"reason": {"cleared"}})
+	requireError(t, nethttp.StatusConflict, "account_not_frozen", status, body)
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/close", url.Values{"reason": {"customer request"}})
+	requireError(t, nethttp.StatusConflict, "account_not_empty", status, body)
+
+	status, _ = send(t, router, nethttp.MethodPost, path+"/withdrawals", money(100, "EUR"))
+	require.Equal(t, nethttp.StatusOK, status)
+	status, body = send(t, router, nethttp.MethodPost, path+"/close", url.Values{"reason": {"customer request"}})
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.Equal(t, "closed", body["status"])
+
+	status, body = send(t, router, nethttp.MethodPost, path+"/deposits", money(10, "EUR"))
+	requireError(t, nethttp.StatusConflict, "account_closed", status, body)
+
+	status, body = send(t, router, nethttp.MethodPost, "/api/v1/accounts/unknown/freeze", url.Values{"reason": {"fraud check"}})
+	requireError(t, nethttp.StatusNotFound, "account_not_found", status, body)
+}
+
+func TestController_Transfers(t *testing.T) {
+	router := newTestRouter(t)
+	from := openAccount(t, router, 100)
+	to := openAccount(t, router, 0)
+
+	transfer := func(from, to string, amount int) (int, map[string]any) {
+		form := money(amount, "EUR")
+		form.Set("from", from)
+		form.Set("to", to)
+		return send(t, router, nethttp.MethodPost, "/api/v1/transfer", form)
+	}
+
+	status, body := transfer(from, to, 30)
+	require.Equal(t, nethttp.StatusOK, status)
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/transfers/"+body["transfer_id"].(string), nil)
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.Equal(t, "completed", body["status"])
+	assert.Equal(t, from, body["from"])
+	assert.EqualValues(t, 30, body["amount"])
+
+	status, body = transfer(from, from, 30)
+	requireError(t, nethttp.StatusBadRequest, "same_account", status, body)
+
+	status, body = transfer(from, to, 500)
+	requireError(t, nethttp.StatusUnprocessableEntity, "insufficient_funds", status, body)
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/transfers/unknown", nil)
+	requireError(t, nethttp.StatusNotFound, "transfer_not_found", status, body)
+}
+
+func TestController_StatementAndBalance(t *testing.T) {
+	router := newTestRouter(t)
+	id := openAccount(t, router, 100)
+	status, _ := send(t, router, nethttp.MethodPost, "/api/v1/accounts/"+id+"/withdrawals", money(30, "EUR"))
+	require.Equal(t, nethttp.StatusOK, status)
+
+	now := time.Now().UTC()
+	from, to := now.Add(-time.Hour).Format(time.RFC3339), now.Add(time.Hour).Format(time.RFC3339)
+	status, body := send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id+"/statement", url.Values{"from": {from}, "to": {to}})
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.EqualValues(t, 0, body["opening_balance"])
+	assert.EqualValues(t, 70, body["closing_balance"])
+	assert.Len(t, body["entries"], 2)
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id+"/statement", url.Values{"from": {"yesterday"}, "to": {to}})
+	requireError(t, nethttp.StatusBadRequest, "malformed_request", status, body)
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id+"/statement", url.Values{"from": {to}, "to": {from}})
+	requireError(t, nethttp.StatusBadRequest, "invalid_date_range", status, body)
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/unknown/statement", url.Values{"from": {from}, "to": {to}})
+	requireError(t, nethttp.StatusNotFound, "account_not_found", status, body)
+
+	at := time.Now().UTC().Add(time.Second).Format(time.RFC3339)
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id+"/balance", url.Values{"at": {at}})
+	require.Equal(t, nethttp.StatusOK, status)
+	assert.EqualValues(t, 70, body["balance"])
+	assert.Equal(t, "EUR", body["currency"])
+
+	status, body = send(t, router, nethttp.MethodGet, "/api/v1/accounts/"+id+"/balance", nil)
+	requireError(t, nethttp.StatusBadRequest, "malformed_request", status, body)
+}
+
+func TestController_Routes(t *testing.T) {
+	router := newTestRouter(t)
+
+	status, _ := send(t, router, nethttp.MethodGet, "/api/v1/accounts/acc1/deposits", nil)
+	assert.Equal(t, nethttp.StatusNotFound, status)
+
+	status, _ = send(t, router, nethttp.MethodPost, "/api/v2/transfer", nil)
+	assert.Equal(t, nethttp.StatusNotFound, status)
+
+	status, _ = send(t, router, nethttp.MethodOptions, "/api/v1/transfer", nil)
+	assert.Equal(t, nethttp.StatusOK, status)
+}
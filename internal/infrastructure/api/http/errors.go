@@ -0,0 +1,12 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
+)
+
+// respondError writes err with its mapped status as {"code": ..., "error": ...}
+func respondError(ctx *gin.Context, err error) {
+	apiErr := api.ToError(err)
+	ctx.JSON(apiErr.HTTPStatus, gin.H{"code": apiErr.Code, "error": apiErr.Message})
+}
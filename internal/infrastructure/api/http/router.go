@@ -1,6 +1,9 @@
 package http
 
 import (
+	"context"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -8,13 +11,15 @@ type Router struct {
 	engine *gin.Engine
 }
 
-func NewRouter() *Router {
+// NewRouter cancels the context of every request after requestTimeout
+func NewRouter(requestTimeout time.Duration) *Router {
 	engine := gin.Default()
 
 	// Add common middleware
 	engine.Use(gin.Recovery())
 	engine.Use(gin.Logger())
 	engine.Use(corsMiddleware())
+	engine.Use(timeoutMiddleware(requestTimeout))
 
 	return &Router{
 		engine: engine,
@@ -29,7 +34,7 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -40,3 +45,15 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// timeoutMiddleware gives the request context a deadline. Handlers pass it
+// down to the use cases, which abandon the request once it expires.
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/infrastructure/api"
+)
+
+// WebhookController serves the registration of webhooks and their delivery
+// logs. Secrets are write-only: they are never sent back.
+type WebhookController struct {
+	registerWebhookUseCase       *usecases.RegisterWebhookUseCase
+	getWebhookUseCase            *usecases.GetWebhookUseCase
+	listWebhooksUseCase          *usecases.ListWebhooksUseCase
+	deleteWebhookUseCase         *usecases.DeleteWebhookUseCase
+	listWebhookDeliveriesUseCase *usecases.ListWebhookDeliveriesUseCase
+	redeliverWebhookUseCase      *usecases.RedeliverWebhookUseCase
+}
+
+func NewWebhookController(
+	registerWebhookUseCase *usecases.RegisterWebhookUseCase,
+	getWebhookUseCase *usecases.GetWebhookUseCase,
+	listWebhooksUseCase *usecases.ListWebhooksUseCase,
+	deleteWebhookUseCase *usecases.DeleteWebhookUseCase,
+	listWebhookDeliveriesUseCase *usecases.ListWebhookDeliveriesUseCase,
+	redeliverWebhookUseCase *usecases.RedeliverWebhookUseCase,
+) *WebhookController {
+	return &WebhookController{
+		registerWebhookUseCase:       registerWebhookUseCase,
+		getWebhookUseCase:            getWebhookUseCase,
+		listWebhooksUseCase:          listWebhooksUseCase,
+		deleteWebhookUseCase:         deleteWebhookUseCase,
+		listWebhookDeliveriesUseCase: listWebhookDeliveriesUseCase,
+		redeliverWebhookUseCase:      redeliverWebhookUseCase,
+	}
+}
+
+func (c *WebhookController) SetupRoutes(router *Router) {
+	api := router.Engine().Group("/api/v1")
+	{
+		api.POST("/webhooks", c.RegisterWebhook)
+		api.GET("/webhooks", c.ListWebhooks)
+		api.GET("/webhooks/:id", c.GetWebhook)
+		api.DELETE("/webhooks/:id", c.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", c.ListDeliveries)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", c.Redeliver)
+	}
+}
+
+// RegisterWebhook takes the url, the secret and one event_types field per event type
+func (c *WebhookController) RegisterWebhook(ctx *gin.Context) {
+	webhook, err := c.registerWebhookUseCase.Execute(ctx.Request.Context(), ctx.PostForm("url"), ctx.PostFormArray("event_types"), ctx.PostForm("secret"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, webhookJSON(webhook))
+}
+
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	webhooks, err := c.listWebhooksUseCase.Execute(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		items = append(items, webhookJSON(webhook))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"webhooks": items})
+}
+
+func (c *WebhookController) GetWebhook(ctx *gin.Context) {
+	webhook, err := c.getWebhookUseCase.Execute(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookJSON(webhook))
+}
+
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	if err := c.deleteWebhookUseCase.Execute(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns the delivery log, newest first, filtered by the
+// status query parameter when given: status=dead lists the dead letters
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	limit := 0
+	if value := ctx.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil {
+			respondError(ctx, api.ErrMalformedRequest)
+			return
+		}
+	}
+
+	status := usecases.DeliveryStatus(ctx.Query("status"))
+	deliveries, err := c.listWebhookDeliveriesUseCase.Execute(ctx.Request.Context(), ctx.Param("id"), status, limit)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		items = append(items, deliveryJSON(delivery))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"deliveries": items})
+}
+
+func (c *WebhookController) Redeliver(ctx *gin.Context) {
+	delivery, err := c.redeliverWebhookUseCase.Execute(ctx.Request.Context(), ctx.Param("id"), ctx.Param("delivery_id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveryJSON(delivery))
+}
+
+func webhookJSON(webhook *usecases.Webhook) gin.H {
+	return gin.H{
+		"id":          webhook.ID,
+		"url":         webhook.URL,
+		"event_types": webhook.EventTypes,
+		"created_at":  webhook.CreatedAt,
+	}
+}
+
+func deliveryJSON(delivery *usecases.WebhookDelivery) gin.H {
+	return gin.H{
+		"id":              delivery.ID,
+		"webhook_id":      delivery.WebhookID,
+		"event_id":        delivery.EventID,
+		"event_type":      delivery.EventType,
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"last_error":      delivery.LastError,
+		"created_at":      delivery.CreatedAt,
+		"updated_at":      delivery.UpdatedAt,
+	}
+}
@@ -0,0 +1,463 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes every environment variable read by Load
+const EnvPrefix = "BANKING_"
+
+type Config struct {
+	HTTP     ListenerConfig `yaml:"http"`
+	GRPC     ListenerConfig `yaml:"grpc"`
+	TLS      TLSConfig      `yaml:"tls"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Locks    LocksConfig    `yaml:"locks"`
+	Events   EventsConfig   `yaml:"events"`
+	Webhooks WebhooksConfig `yaml:"webhooks"`
+	Features FeaturesConfig `yaml:"features"`
+	// RequestTimeout bounds how long a request runs before it is canceled
+	// and its transaction rolled back
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// ShutdownTimeout bounds how long in-flight requests get to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// UsesRedis reports whether anything configured needs Redis. Only the
+// MySQL and Postgres drivers cache accounts in it.
+func (c Config) UsesRedis() bool {
+	return c.Database.Driver == DriverMySQL ||
+		c.Database.Driver == DriverPostgres ||
+		c.Features.DistributedLocks ||
+		c.Events.Publisher == PublisherRedis
+}
+
+type ListenerConfig struct {
+	Addr string `yaml:"addr"`
+}
+
+// TLSConfig enables TLS on both listeners when both files are set
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// Database drivers
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
+
+// Database locking modes
+const (
+	LockingPessimistic = "pessimistic"
+	LockingOptimistic  = "optimistic"
+)
+
+// Account storage modes
+const (
+	AccountStorageState  = "state"
+	AccountStorageEvents = "events"
+)
+
+type DatabaseConfig struct {
+	// Driver selects the storage: MySQL, Postgres, SQLite for a single
+	// node, or memory for tests and local development, which loses
+	// everything on restart
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+	// Path is the database file of the SQLite driver
+	Path string `yaml:"path"`
+	// Locking is pessimistic to lock the accounts a transaction reads, or
+	// optimistic to only check nobody saved them since, when writing
+	Locking string `yaml:"locking"`
+	// AccountStorage is state to store the current state of accounts, or
+	// events to store the events they raised and replay them. Only the SQL
+	// drivers store events.
+	AccountStorage string `yaml:"account_storage"`
+	// SnapshotEvery is how many events of an account are replayed at most,
+	// when stored as events
+	SnapshotEvery   int           `yaml:"snapshot_every"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+}
+
+type RedisConfig struct {
+	Addr     string        `yaml:"addr"`
+	Password string        `yaml:"password"`
+	DB       int           `yaml:"db"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+// LocksConfig tunes the distributed account locks
+type LocksConfig struct {
+	// TTL is how long a lock outlives a holder that stopped renewing it
+	TTL time.Duration `yaml:"ttl"`
+	// Wait is how long to wait for a lock held by someone else
+	Wait time.Duration `yaml:"wait"`
+}
+
+// Event publishers
+const (
+	PublisherNone  = "none"
+	PublisherRedis = "redis"
+)
+
+// EventsConfig tunes the relay publishing the domain events of the outbox
+type EventsConfig struct {
+	// Publisher is redis to append the events to a Redis stream, or none to
+	// keep them in the outbox until a publisher is configured
+	Publisher string `yaml:"publisher"`
+	// Stream is the Redis stream the events are appended to
+	Stream string `yaml:"stream"`
+	// RelayInterval is how long the relay waits once the outbox is empty
+	RelayInterval time.Duration `yaml:"relay_interval"`
+	// RelayLease is how long a relay keeps the others from publishing once
+	// it claimed a batch, which must outlast publishing it
+	RelayLease time.Duration `yaml:"relay_lease"`
+	// BatchSize is how many events the relay publishes per batch
+	BatchSize int `yaml:"batch_size"`
+}
+
+// WebhooksConfig tunes the delivery of events to the registered webhooks
+type WebhooksConfig struct {
+	// Timeout is how long a webhook gets to answer
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts is how many attempts a delivery gets before it is dead
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every other one up to MaxBackoff
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Interval is how long the deliverer waits once nothing is due
+	Interval time.Duration `yaml:"interval"`
+	// BatchSize is how many deliveries are sent side by side
+	BatchSize int `yaml:"batch_size"`
+	// AllowPrivateNetworks lets webhooks be registered on, and delivered to,
+	// loopback, private and link-local addresses, for local development
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+}
+
+type FeaturesConfig struct {
+	// FXTransfers allows transfers between accounts in different currencies
+	FXTransfers bool `yaml:"fx_transfers"`
+	// GRPCReflection registers the gRPC reflection service
+	GRPCReflection bool `yaml:"grpc_reflection"`
+	// DistributedLocks locks accounts in Redis during transfers, for
+	// deployments running several instances
+	DistributedLocks bool `yaml:"distributed_locks"`
+	// Webhooks pushes events to the webhooks registered through the HTTP API
+	Webhooks bool `yaml:"webhooks"`
+}
+
+func Default() Config {
+	return Config{
+		HTTP: ListenerConfig{Addr: ":8080"},
+		GRPC: ListenerConfig{Addr: ":9090"},
+		Database: DatabaseConfig{
+			Driver:          DriverMySQL,
+			DSN:             "user:password@tcp(localhost:3306)/banking?parseTime=true",
+			Locking:         LockingPessimistic,
+			AccountStorage:  AccountStorageState,
+			SnapshotEvery:   100,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Redis: RedisConfig{
+			Addr:     "localhost:6379",
+			CacheTTL: 1 * time.Hour,
+		},
+		Locks: LocksConfig{
+			TTL:  10 * time.Second,
+			Wait: 5 * time.Second,
+		},
+		Events: EventsConfig{
+			Publisher:     PublisherNone,
+			Stream:        "banking-events",
+			RelayInterval: 1 * time.Second,
+			RelayLease:    30 * time.Second,
+			BatchSize:     100,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:     10 * time.Second,
+			MaxAttempts: 8,
+			Backoff:     10 * time.Second,
+			MaxBackoff:  1 * time.Hour,
+			Interval:    1 * time.Second,
+			BatchSize:   20,
+		},
+		Features: FeaturesConfig{
+			FXTransfers:    true,
+			GRPCReflection: true,
+		},
+		RequestTimeout:  10 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// Load builds the configuration from the defaults, then the YAML file named
+// by -config or BANKING_CONFIG, then BANKING_* environment variables and
+// finally the command line flags, each overriding the previous one.
+func Load(args []string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", os.Getenv(EnvPrefix+"CONFIG"), "path to a YAML configuration file")
+	values := make(map[string]*string)
+	for _, s := range settings(&cfg) {
+		values[s.name] = fs.String(s.name, "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings(&cfg) {
+		if value, ok := os.LookupEnv(s.env()); ok {
+			if err := s.set(value); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", s.env(), err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings(&cfg) {
+			if s.name == f.Name && flagErr == nil {
+				if err := s.set(*values[s.name]); err != nil {
+					flagErr = fmt.Errorf("-%s: %w", s.name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return Config{}, flagErr
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	}
+	if c.GRPC.Addr == "" {
+		errs = append(errs, errors.New("grpc.addr is required"))
+	}
+	if c.HTTP.Addr == c.GRPC.Addr {
+		errs = append(errs, errors.New("http.addr and grpc.addr must differ"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+		if _, err := os.Stat(file); file != "" && err != nil {
+			errs = append(errs, fmt.Errorf("tls: %w", err))
+		}
+	}
+	switch c.Database.Driver {
+	case DriverMySQL, DriverPostgres:
+		if c.Database.DSN == "" {
+			errs = append(errs, errors.New("database.dsn is required"))
+		}
+	case DriverSQLite:
+		if c.Database.Path == "" {
+			errs = append(errs, errors.New("database.path is required"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.driver %q is not supported", c.Database.Driver))
+	}
+	if c.Database.Locking != LockingPessimistic && c.Database.Locking != LockingOptimistic {
+		errs = append(errs, fmt.Errorf("database.locking %q is not supported", c.Database.Locking))
+	}
+	switch c.Database.AccountStorage {
+	case AccountStorageState:
+	case AccountStorageEvents:
+		if c.Database.Driver == DriverMemory {
+			errs = append(errs, errors.New("database.account_storage events needs a SQL driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("database.account_storage %q is not supported", c.Database.AccountStorage))
+	}
+	if c.Database.SnapshotEvery < 1 {
+		errs = append(errs, errors.New("database.snapshot_every must be positive"))
+	}
+	if c.Database.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("database.max_open_conns must be positive"))
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns must be between 0 and max_open_conns"))
+	}
+	if c.UsesRedis() && c.Redis.Addr == "" {
+		errs = append(errs, errors.New("redis.addr is required"))
+	}
+	if c.Redis.CacheTTL <= 0 {
+		errs = append(errs, errors.New("redis.cache_ttl must be positive"))
+	}
+	if c.Locks.TTL <= 0 {
+		errs = append(errs, errors.New("locks.ttl must be positive"))
+	}
+	if c.Locks.Wait < 0 {
+		errs = append(errs, errors.New("locks.wait must not be negative"))
+	}
+	switch c.Events.Publisher {
+	case PublisherRedis:
+		if c.Events.Stream == "" {
+			errs = append(errs, errors.New("events.stream is required"))
+		}
+	case PublisherNone:
+	default:
+		errs = append(errs, fmt.Errorf("events.publisher %q is not supported", c.Events.Publisher))
+	}
+	if c.Events.RelayInterval <= 0 {
+		errs = append(errs, errors.New("events.relay_interval must be positive"))
+	}
+	if c.Events.RelayLease <= 0 {
+		errs = append(errs, errors.New("events.relay_lease must be positive"))
+	}
+	if c.Events.BatchSize < 1 {
+		errs = append(errs, errors.New("events.batch_size must be positive"))
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout must be positive"))
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		errs = append(errs, errors.New("webhooks.max_attempts must be positive"))
+	}
+	if c.Webhooks.Backoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		errs = append(errs, errors.New("webhooks.backoff must be positive and at most webhooks.max_backoff"))
+	}
+	if c.Webhooks.Interval <= 0 {
+		errs = append(errs, errors.New("webhooks.interval must be positive"))
+	}
+	if c.Webhooks.BatchSize < 1 {
+		errs = append(errs, errors.New("webhooks.batch_size must be positive"))
+	}
+	if c.RequestTimeout <= 0 {
+		errs = append(errs, errors.New("request_timeout must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// setting is a configuration value that can be overridden from the
+// environment and the command line
+type setting struct {
+	name  string
+	usage string
+	set   func(string) error
+}
+
+// env turns a flag name such as redis-cache-ttl into BANKING_REDIS_CACHE_TTL
+func (s setting) env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.name, "-", "_"))
+}
+
+func settings(cfg *Config) []setting {
+	return []setting{
+		{"http-addr", "HTTP listen address", setString(&cfg.HTTP.Addr)},
+		{"grpc-addr", "gRPC listen address", setString(&cfg.GRPC.Addr)},
+		{"tls-cert-file", "TLS certificate file", setString(&cfg.TLS.CertFile)},
+		{"tls-key-file", "TLS key file", setString(&cfg.TLS.KeyFile)},
+		{"database-driver", "storage driver: mysql, postgres, sqlite or memory", setString(&cfg.Database.Driver)},
+		{"database-dsn", "MySQL or Postgres data source name", setString(&cfg.Database.DSN)},
+		{"database-path", "SQLite database file", setString(&cfg.Database.Path)},
+		{"database-locking", "how transactions lock accounts: pessimistic or optimistic", setString(&cfg.Database.Locking)},
+		{"database-account-storage", "how accounts are stored: state or events", setString(&cfg.Database.AccountStorage)},
+		{"database-snapshot-every", "how many events of an account stored as events are replayed at most", setInt(&cfg.Database.SnapshotEvery)},
+		{"database-max-open-conns", "maximum open database connections", setInt(&cfg.Database.MaxOpenConns)},
+		{"database-max-idle-conns", "maximum idle database connections", setInt(&cfg.Database.MaxIdleConns)},
+		{"database-conn-max-lifetime", "maximum lifetime of a database connection", setDuration(&cfg.Database.ConnMaxLifetime)},
+		{"redis-addr", "Redis address", setString(&cfg.Redis.Addr)},
+		{"redis-password", "Redis password", setString(&cfg.Redis.Password)},
+		{"redis-db", "Redis database number", setInt(&cfg.Redis.DB)},
+		{"redis-cache-ttl", "how long accounts stay cached in Redis", setDuration(&cfg.Redis.CacheTTL)},
+		{"locks-ttl", "how long a lock outlives a holder that stopped renewing it", setDuration(&cfg.Locks.TTL)},
+		{"locks-wait", "how long to wait for a lock held by someone else", setDuration(&cfg.Locks.Wait)},
+		{"events-publisher", "where domain events are published: redis or none", setString(&cfg.Events.Publisher)},
+		{"events-stream", "Redis stream the domain events are appended to", setString(&cfg.Events.Stream)},
+		{"events-relay-interval", "how long the event relay waits once the outbox is empty", setDuration(&cfg.Events.RelayInterval)},
+		{"events-relay-lease", "how long the event relay keeps the others from publishing", setDuration(&cfg.Events.RelayLease)},
+		{"events-batch-size", "how many events the relay publishes per batch", setInt(&cfg.Events.BatchSize)},
+		{"webhooks-timeout", "how long a webhook gets to answer", setDuration(&cfg.Webhooks.Timeout)},
+		{"webhooks-max-attempts", "how many attempts a webhook delivery gets", setInt(&cfg.Webhooks.MaxAttempts)},
+		{"webhooks-backoff", "wait after the first failed webhook delivery", setDuration(&cfg.Webhooks.Backoff)},
+		{"webhooks-max-backoff", "longest wait between webhook delivery attempts", setDuration(&cfg.Webhooks.MaxBackoff)},
+		{"webhooks-interval", "how long the webhook deliverer waits once nothing is due", setDuration(&cfg.Webhooks.Interval)},
+		{"webhooks-batch-size", "how many webhook deliveries are sent side by side", setInt(&cfg.Webhooks.BatchSize)},
+		{"webhooks-allow-private-networks", "allow webhooks on loopback, private and link-local addresses", setBool(&cfg.Webhooks.AllowPrivateNetworks)},
+		{"features-fx-transfers", "allow transfers across currencies", setBool(&cfg.Features.FXTransfers)},
+		{"features-grpc-reflection", "register the gRPC reflection service", setBool(&cfg.Features.GRPCReflection)},
+		{"features-distributed-locks", "lock accounts in Redis during transfers", setBool(&cfg.Features.DistributedLocks)},
+		{"features-webhooks", "push events to the registered webhooks", setBool(&cfg.Features.Webhooks)},
+		{"request-timeout", "how long a request runs before it is canceled", setDuration(&cfg.RequestTimeout)},
+		{"shutdown-timeout", "how long in-flight requests get to finish on shutdown", setDuration(&cfg.ShutdownTimeout)},
+	}
+}
+
+func setString(dst *string) func(string) error {
+	return func(value string) error {
+		*dst = value
+		return nil
+	}
+}
+
+func setInt(dst *int) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func setBool(dst *bool) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = strconv.ParseBool(value)
+		return err
+	}
+}
+
+func setDuration(dst *time.Duration) func(string) error {
+	return func(value string) (err error) {
+		*dst, err = time.ParseDuration(value)
+		return err
+	}
+}
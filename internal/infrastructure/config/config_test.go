@@ -0,0 +1,163 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/infrastructure/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// clearEnv unsets the BANKING_* variables for the duration of the test, such
+// as those choosing the database of the other tests, as Load reads them all
+func clearEnv(t *testing.T) {
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, config.EnvPrefix) {
+			// Setenv restores the variable once the test is over
+			t.Setenv(name, "")
+			require.NoError(t, os.Unsetenv(name))
+		}
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+http:
+  addr: ":8000"
+grpc:
+  addr: ":9000"
+redis:
+  addr: "redis:6379"
+  cache_ttl: 10m
+features:
+  fx_transfers: false
+`)
+	clearEnv(t)
+	t.Setenv("BANKING_CONFIG", path)
+	t.Setenv("BANKING_GRPC_ADDR", ":9100")
+	t.Setenv("BANKING_REDIS_CACHE_TTL", "20m")
+
+	cfg, err := config.Load([]string{"-redis-cache-ttl", "30m"})
+	require.NoError(t, err)
+
+	// File over defaults
+	assert.Equal(t, ":8000", cfg.HTTP.Addr)
+	assert.Equal(t, "redis:6379", cfg.Redis.Addr)
+	assert.False(t, cfg.Features.FXTransfers)
+	assert.True(t, cfg.Features.GRPCReflection)
+	// Environment over file
+	assert.Equal(t, ":9100", cfg.GRPC.Addr)
+	// Flags over environment
+	assert.Equal(t, 30*time.Minute, cfg.Redis.CacheTTL)
+}
+
+func TestLoadErrors(t *testing.T) {
+	clearEnv(t)
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := config.Load([]string{"-config", filepath.Join(t.TempDir(), "missing.yaml")})
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		_, err := config.Load([]string{"-config", writeFile(t, "redis: [")})
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed environment value", func(t *testing.T) {
+		t.Setenv("BANKING_DATABASE_MAX_OPEN_CONNS", "many")
+		_, err := config.Load(nil)
+		assert.ErrorContains(t, err, "BANKING_DATABASE_MAX_OPEN_CONNS")
+	})
+
+	t.Run("malformed flag value", func(t *testing.T) {
+		_, err := config.Load([]string{"-shutdown-timeout", "soon"})
+		assert.ErrorContains(t, err, "-shutdown-timeout")
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		_, err := config.Load([]string{"-verbose"})
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*config.Config)
+	}{
+		{"missing HTTP address", func(c *config.Config) { c.HTTP.Addr = "" }},
+		{"shared listen address", func(c *config.Config) { c.GRPC.Addr = c.HTTP.Addr }},
+		{"certificate without key", func(c *config.Config) { c.TLS.CertFile = "server.crt" }},
+		{"missing certificate file", func(c *config.Config) {
+			c.TLS.CertFile, c.TLS.KeyFile = "missing.crt", "missing.key"
+		}},
+		{"unknown driver", func(c *config.Config) { c.Database.Driver = "oracle" }},
+		{"missing DSN", func(c *config.Config) { c.Database.DSN = "" }},
+		{"missing Postgres DSN", func(c *config.Config) {
+			c.Database.Driver, c.Database.DSN = config.DriverPostgres, ""
+		}},
+		{"unknown locking", func(c *config.Config) { c.Database.Locking = "hopeful" }},
+		{"unknown account storage", func(c *config.Config) { c.Database.AccountStorage = "ledger" }},
+		{"account events in memory", func(c *config.Config) {
+			c.Database.Driver, c.Database.AccountStorage = config.DriverMemory, config.AccountStorageEvents
+		}},
+		{"no snapshots", func(c *config.Config) { c.Database.SnapshotEvery = 0 }},
+		{"no connections", func(c *config.Config) { c.Database.MaxOpenConns = 0 }},
+		{"more idle than open connections", func(c *config.Config) { c.Database.MaxIdleConns = c.Database.MaxOpenConns + 1 }},
+		{"missing Redis address", func(c *config.Config) { c.Redis.Addr = "" }},
+		{"missing SQLite path", func(c *config.Config) { c.Database.Driver = config.DriverSQLite }},
+		{"distributed locks without Redis", func(c *config.Config) {
+			c.Database.Driver, c.Database.Path = config.DriverSQLite, "banking.db"
+			c.Features.DistributedLocks, c.Redis.Addr = true, ""
+		}},
+		{"no cache TTL", func(c *config.Config) { c.Redis.CacheTTL = 0 }},
+		{"no lock TTL", func(c *config.Config) { c.Locks.TTL = 0 }},
+		{"unknown event publisher", func(c *config.Config) { c.Events.Publisher = "kafka" }},
+		{"no event stream", func(c *config.Config) { c.Events.Publisher = config.PublisherRedis; c.Events.Stream = "" }},
+		{"no relay interval", func(c *config.Config) { c.Events.RelayInterval = 0 }},
+		{"no relay lease", func(c *config.Config) { c.Events.RelayLease = 0 }},
+		{"no webhook timeout", func(c *config.Config) { c.Webhooks.Timeout = 0 }},
+		{"no webhook attempts", func(c *config.Config) { c.Webhooks.MaxAttempts = 0 }},
+		{"webhook backoff over its maximum", func(c *config.Config) { c.Webhooks.MaxBackoff = c.Webhooks.Backoff / 2 }},
+		{"no request timeout", func(c *config.Config) { c.RequestTimeout = 0 }},
+		{"no shutdown timeout", func(c *config.Config) { c.ShutdownTimeout = 0 }},
+	}
+
+	require.NoError(t, config.Default().Validate())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			tt.change(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestValidate_SQLiteWithoutRedis(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Driver, cfg.Database.DSN, cfg.Database.Path = config.DriverSQLite, "", "banking.db"
+	cfg.Redis.Addr = ""
+
+	require.NoError(t, cfg.Validate())
+	assert.False(t, cfg.UsesRedis())
+}
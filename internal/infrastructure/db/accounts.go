@@ -4,51 +4,103 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"time"
 
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
 	"github.com/ppicom/newtonian/internal/domain/banking"
 	"github.com/redis/go-redis/v9"
 )
 
-const findAccountQuery = `SELECT id, balance FROM accounts WHERE id = ? FOR UPDATE`
-const saveAccountQuery = `INSERT INTO accounts (id, balance) VALUES (?, ?) 
-								ON DUPLICATE KEY UPDATE balance = ?`
+const findAccountQuery = `SELECT id, owner, balance, currency, status, version FROM accounts WHERE id = ?`
+const lockAccountQuery = findAccountQuery + ` FOR UPDATE`
+const insertAccountQuery = `INSERT INTO accounts (id, owner, balance, currency, status, version, fence_token, opened_at) 
+								VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+const updateAccountQuery = `UPDATE accounts SET owner = ?, balance = ?, currency = ?, status = ?, version = ? 
+								WHERE id = ? AND version = ?`
+const updateFencedAccountQuery = `UPDATE accounts SET owner = ?, balance = ?, currency = ?, status = ?, version = ?, fence_token = ? 
+								WHERE id = ? AND version = ? AND fence_token <= ?`
+const findAccountFenceQuery = `SELECT fence_token FROM accounts WHERE id = ?`
+const findAccountOpenedAtQuery = `SELECT opened_at FROM accounts WHERE id = ?`
+const saveStatusChangeQuery = `INSERT INTO account_status_changes (account_id, from_status, to_status, reason, actor, created_at) 
+								VALUES (?, ?, ?, ?, ?, ?)`
+const saveLedgerEntryQuery = `INSERT INTO ledger_entries (journal_id, account_id, amount, currency, created_at) 
+								VALUES (?, ?, ?, ?, ?)`
+const findLedgerEntriesQuery = `SELECT journal_id, account_id, amount, currency, created_at FROM ledger_entries 
+								WHERE account_id = ? ORDER BY id`
+const findLedgerEntriesSinceQuery = `SELECT journal_id, account_id, amount, currency, created_at FROM ledger_entries 
+								WHERE account_id = ? AND created_at >= ? ORDER BY id`
+const findLedgerEntriesBetweenQuery = `SELECT journal_id, account_id, amount, currency, created_at FROM ledger_entries 
+								WHERE account_id = ? AND created_at >= ? AND created_at < ? ORDER BY id`
+const saveDailyBalanceQuery = `INSERT INTO daily_balances (account_id, day, balance) VALUES (?, ?, ?)`
+const findDailyBalanceQuery = `SELECT day, balance FROM daily_balances WHERE account_id = ? AND day >= ? 
+								ORDER BY day LIMIT 1`
+const listAccountsQuery = `SELECT id, owner, balance, currency, status, version FROM accounts WHERE id > ? ORDER BY id LIMIT ?`
 
+// setCacheScript caches an account unless the cache already holds the same or a newer version of it.
+// A cached account without a version, as written before accounts had one, counts as version 0.
+var setCacheScript = redis.NewScript(`
+local cached = redis.call("GET", KEYS[1])
+if cached and (cjson.decode(cached).Version or 0) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+return 1
+`)
+
+// AccountRepository stores accounts in a SQL database and caches them in Redis for
+// reads outside transactions. The cache only ever holds committed data: it
+// is updated once a transaction commits and invalidated when it rolls back,
+// and never goes back to an older version of an account. Without a Redis
+// client, every read goes to the database.
 type AccountRepository struct {
-	db    *sql.DB
-	redis *redis.Client
+	db       *sql.DB
+	dialect  Dialect
+	redis    *redis.Client
+	cacheTTL time.Duration
+	unit     *unit
 }
 
-func (r *AccountRepository) BeginTx() (*sql.Tx, error) {
-	return r.db.BeginTx(context.Background(), &sql.TxOptions{
-		Isolation: sql.LevelSerializable,
-	})
+// inTx returns the repository bound to the transaction of u
+func (r *AccountRepository) inTx(u *unit) AccountStore {
+	return r.bind(u)
 }
 
-func (r *AccountRepository) CommitTx(tx *sql.Tx) error {
-	return tx.Commit()
+func (r *AccountRepository) bind(u *unit) *AccountRepository {
+	bound := *r
+	bound.unit = u
+	return &bound
 }
 
-func (r *AccountRepository) RollbackTx(tx *sql.Tx) error {
-	return tx.Rollback()
-}
+// Find reads through the cache outside a transaction. Inside one, it always
+// reads the row, and locks it when locking pessimistically, so the balance
+// cannot change until the end of it.
+func (r *AccountRepository) Find(ctx context.Context, id string) (*banking.Account, error) {
+	if r.unit != nil && r.unit.locking == Optimistic {
+		return r.findInDatabase(ctx, findAccountQuery, id)
+	}
+	if r.unit != nil {
+		return r.findInDatabase(ctx, lockAccountQuery, id)
+	}
 
-func (r *AccountRepository) Find(tx *sql.Tx, id string) (*banking.Account, error) {
-	if account, err := r.findInCache(id); err == nil {
+	if account, err := r.findInCache(ctx, id); err == nil {
 		return account, nil
 	}
 
-	account, err := r.findInDatabase(tx, id)
+	account, err := r.findInDatabase(ctx, findAccountQuery, id)
 	if err != nil {
 		return nil, err
 	}
 
-	r.updateCache(account)
+	r.fillCache(ctx, account)
 	return account, nil
 }
 
-func (r *AccountRepository) findInCache(id string) (*banking.Account, error) {
-	ctx := context.Background()
+func (r *AccountRepository) findInCache(ctx context.Context, id string) (*banking.Account, error) {
+	if r.redis == nil {
+		return nil, redis.Nil
+	}
+
 	data, err := r.redis.Get(ctx, "account:"+id).Bytes()
 	if err != nil {
 		return nil, err
@@ -61,49 +113,264 @@ func (r *AccountRepository) findInCache(id string) (*banking.Account, error) {
 	return &account, nil
 }
 
-func (r *AccountRepository) findInDatabase(tx *sql.Tx, id string) (*banking.Account, error) {
+func (r *AccountRepository) findInDatabase(ctx context.Context, query, id string) (*banking.Account, error) {
 	var account banking.Account
-	var row *sql.Row
-	if tx != nil {
-		row = tx.QueryRow(findAccountQuery, id)
-	} else {
-		row = r.db.QueryRow(findAccountQuery, id)
+	row := r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, query, id)
+	err := row.Scan(&account.ID, &account.Owner, &account.Balance, &account.Currency, &account.Status, &account.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, banking.ErrAccountNotFound
 	}
-	err := row.Scan(&account.ID, &account.Balance)
 	if err != nil {
-		return nil, err
+		return nil, translate(err)
 	}
 	return &account, nil
 }
 
-func (r *AccountRepository) Save(tx *sql.Tx, account *banking.Account) error {
-	if err := r.saveToDatabase(tx, account); err != nil {
-		return err
+// List returns up to limit accounts ordered by ID, starting after the given one
+func (r *AccountRepository) List(ctx context.Context, after string, limit int) ([]*banking.Account, error) {
+	rows, err := r.unit.querier(r.db, r.dialect).QueryContext(ctx, listAccountsQuery, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []*banking.Account
+	for rows.Next() {
+		var account banking.Account
+		if err := rows.Scan(&account.ID, &account.Owner, &account.Balance, &account.Currency, &account.Status, &account.Version); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, &account)
+	}
+	return accounts, rows.Err()
+}
+
+// Save writes the account with its pending ledger entries, status changes
+// and events, and increments its version. It fails with
+// usecases.ErrConcurrentModification if the account was saved by someone
+// else since it was read. Outside a transaction, it runs in one of its own.
+func (r *AccountRepository) Save(ctx context.Context, account *banking.Account) error {
+	return r.save(ctx, account, 0)
+}
+
+// SaveFenced saves the account as Save does, recording the fencing token it
+// is saved under. It fails with usecases.ErrLockLost if the account was
+// saved under a greater token since.
+func (r *AccountRepository) SaveFenced(ctx context.Context, account *banking.Account, token int64) error {
+	return r.save(ctx, account, token)
+}
+
+// save fences the save with token, unless it is 0
+func (r *AccountRepository) save(ctx context.Context, account *banking.Account, token int64) error {
+	if r.unit == nil {
+		return withinTx(ctx, r.db, Pessimistic, func(u *unit) error {
+			return r.bind(u).save(ctx, account, token)
+		})
+	}
+
+	if err := r.saveToDatabase(ctx, account, token); err != nil {
+		return translate(err)
+	}
+
+	if err := saveHistory(ctx, r.unit.querier(r.db, r.dialect), r.dialect, account); err != nil {
+		return translate(err)
+	}
+
+	// The cache must follow the database even when ctx is canceled right
+	// after the commit or is the reason for the rollback
+	ctx = context.WithoutCancel(ctx)
+
+	// Snapshot the account now, as the caller may keep changing it
+	if data, err := json.Marshal(account); err == nil {
+		version := account.Version
+		r.unit.hooks.afterCommit(func() { r.setCache(ctx, account.ID, version, data) })
+	}
+	r.unit.hooks.afterRollback(func() { r.invalidateCache(ctx, account.ID) })
+	return nil
+}
+
+// FindLedgerEntries returns the ledger history of an account in the order it was written
+func (r *AccountRepository) FindLedgerEntries(ctx context.Context, accountID string) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesQuery, accountID)
+}
+
+// FindLedgerEntriesSince returns the ledger entries of an account written at or after since
+func (r *AccountRepository) FindLedgerEntriesSince(ctx context.Context, accountID string, since time.Time) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesSinceQuery, accountID, since)
+}
+
+// FindLedgerEntriesBetween returns the ledger entries of an account written within [from, to)
+func (r *AccountRepository) FindLedgerEntriesBetween(ctx context.Context, accountID string, from, to time.Time) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesBetweenQuery, accountID, from, to)
+}
+
+// FindDailyBalance returns the balance of the account at the end of the first day on or after day it was saved
+func (r *AccountRepository) FindDailyBalance(ctx context.Context, accountID string, day time.Time) (banking.DailyBalance, error) {
+	return findDailyBalance(ctx, r.unit.querier(r.db, r.dialect), accountID, day)
+}
+
+// FindOpenedAt returns when the account was first saved, or the zero time if it was saved before that was recorded
+func (r *AccountRepository) FindOpenedAt(ctx context.Context, accountID string) (time.Time, error) {
+	return findOpenedAt(ctx, r.unit.querier(r.db, r.dialect), findAccountOpenedAtQuery, accountID)
+}
+
+func queryLedgerEntries(ctx context.Context, q querier, query string, args ...any) ([]banking.LedgerEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	r.updateCache(account)
+	var entries []banking.LedgerEntry
+	for rows.Next() {
+		var entry banking.LedgerEntry
+		if err := rows.Scan(&entry.JournalID, &entry.AccountID, &entry.Amount, &entry.Currency, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// saveToDatabase inserts an account never saved, and otherwise updates it
+// only if it still has the version it was read at and, when fenced by a
+// token, was not saved under a greater one
+func (r *AccountRepository) saveToDatabase(ctx context.Context, account *banking.Account, token int64) error {
+	q := r.unit.querier(r.db, r.dialect)
+	version := account.Version + 1
+	if account.Version == 0 {
+		args := []any{account.ID, account.Owner, account.Balance, account.Currency, account.Status, version, token, time.Now().UTC()}
+		if _, err := q.ExecContext(ctx, insertAccountQuery, args...); err != nil {
+			return err
+		}
+		account.Version = version
+		return nil
+	}
+
+	query := updateAccountQuery
+	args := []any{account.Owner, account.Balance, account.Currency, account.Status, version, account.ID, account.Version}
+	if token > 0 {
+		query = updateFencedAccountQuery
+		args = []any{account.Owner, account.Balance, account.Currency, account.Status, version, token, account.ID, account.Version, token}
+	}
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return err
+	} else if updated == 0 {
+		return notUpdated(ctx, q, findAccountFenceQuery, account.ID, token)
+	}
+	account.Version = version
 	return nil
 }
 
-func (r *AccountRepository) saveToDatabase(tx *sql.Tx, account *banking.Account) error {
-	if tx != nil {
-		_, err := tx.Exec(saveAccountQuery, account.ID, account.Balance, account.Balance)
+// notUpdated tells why a save updated nothing: usecases.ErrLockLost if the
+// row, read with fenceQuery, was saved under a greater fencing token than
+// token, and usecases.ErrConcurrentModification otherwise
+func notUpdated(ctx context.Context, q querier, fenceQuery, id string, token int64) error {
+	if token == 0 {
+		return usecases.ErrConcurrentModification
+	}
+
+	var fence int64
+	if err := q.QueryRowContext(ctx, fenceQuery, id).Scan(&fence); err != nil {
+		return err
+	}
+	if fence > token {
+		return usecases.ErrLockLost
+	}
+	return usecases.ErrConcurrentModification
+}
+
+func findDailyBalance(ctx context.Context, q querier, accountID string, day time.Time) (banking.DailyBalance, error) {
+	daily := banking.DailyBalance{AccountID: accountID}
+	err := q.QueryRowContext(ctx, findDailyBalanceQuery, accountID, day).Scan(&daily.Day, &daily.Balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return banking.DailyBalance{}, usecases.ErrNoDailyBalance
+	}
+	if err != nil {
+		return banking.DailyBalance{}, translate(err)
+	}
+	daily.Day = daily.Day.UTC()
+	return daily, nil
+}
+
+func findOpenedAt(ctx context.Context, q querier, query, accountID string) (time.Time, error) {
+	var opened sql.NullTime
+	err := q.QueryRowContext(ctx, query, accountID).Scan(&opened)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, banking.ErrAccountNotFound
+	}
+	if err != nil {
+		return time.Time{}, translate(err)
+	}
+	return opened.Time.UTC(), nil
+}
+
+// saveHistory writes the pending ledger entries, status changes and events
+// of an account, and the balance it ends the day with, whichever way the
+// account itself is stored
+func saveHistory(ctx context.Context, q querier, dialect Dialect, account *banking.Account) error {
+	daily := account.DailyBalance()
+	query := dialect.upsert(saveDailyBalanceQuery, "account_id, day", "balance")
+	if _, err := q.ExecContext(ctx, query, daily.AccountID, daily.Day, daily.Balance); err != nil {
 		return err
 	}
-	_, err := r.db.Exec(saveAccountQuery, account.ID, account.Balance, account.Balance)
-	return err
+
+	for _, entry := range account.PendingEntries() {
+		args := []any{entry.JournalID, entry.AccountID, entry.Amount, entry.Currency, entry.CreatedAt}
+		if _, err := q.ExecContext(ctx, saveLedgerEntryQuery, args...); err != nil {
+			return err
+		}
+	}
+	account.ClearPendingEntries()
+
+	for _, change := range account.PendingStatusChanges() {
+		args := []any{change.AccountID, change.From, change.To, change.Reason, change.Actor, change.CreatedAt}
+		if _, err := q.ExecContext(ctx, saveStatusChangeQuery, args...); err != nil {
+			return err
+		}
+	}
+	account.ClearPendingStatusChanges()
+
+	if err := saveEvents(ctx, q, account.PendingEvents()); err != nil {
+		return err
+	}
+	account.ClearPendingEvents()
+	return nil
+}
+
+// fillCache caches an account read from the database. Like every cache
+// write, it is a compare-and-set on the version, so a slow reader cannot
+// put an older balance back.
+func (r *AccountRepository) fillCache(ctx context.Context, account *banking.Account) {
+	if data, err := json.Marshal(account); err == nil {
+		r.setCache(ctx, account.ID, account.Version, data)
+	}
+}
+
+func (r *AccountRepository) setCache(ctx context.Context, id string, version int, data []byte) {
+	if r.redis == nil {
+		return
+	}
+	keys := []string{"account:" + id}
+	setCacheScript.Run(ctx, r.redis, keys, data, version, r.cacheTTL.Milliseconds())
 }
 
-func (r *AccountRepository) updateCache(account *banking.Account) {
-	ctx := context.Background()
-	if accountJson, err := json.Marshal(account); err == nil {
-		r.redis.Set(ctx, "account:"+account.ID, accountJson, 1*time.Hour)
+func (r *AccountRepository) invalidateCache(ctx context.Context, id string) {
+	if r.redis == nil {
+		return
 	}
+	r.redis.Del(ctx, "account:"+id)
 }
 
-func NewAccountRepository(db *sql.DB, redis *redis.Client) *AccountRepository {
+func NewAccountRepository(db *sql.DB, dialect Dialect, redis *redis.Client, cacheTTL time.Duration) *AccountRepository {
 	return &AccountRepository{
-		db:    db,
-		redis: redis,
+		db:       db,
+		dialect:  dialect,
+		redis:    redis,
+		cacheTTL: cacheTTL,
 	}
 }
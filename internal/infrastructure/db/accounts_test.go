@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountRepository_DailyBalances(t *testing.T) {
+	migrator, conn := newTestMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	repo := NewAccountRepository(conn, SQLite, nil, time.Hour)
+
+	account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+	require.NoError(t, repo.Save(t.Context(), account))
+	require.NoError(t, banking.Deposit(account, 20))
+	require.NoError(t, repo.Save(t.Context(), account))
+
+	var days int
+	require.NoError(t, conn.QueryRow(`SELECT COUNT(*) FROM daily_balances`).Scan(&days))
+	assert.Equal(t, 1, days, "the saves of a day keep a single balance")
+
+	today := banking.StartOfDay(time.Now())
+	lastWeek := today.AddDate(0, 0, -7)
+	_, err = conn.Exec(`INSERT INTO daily_balances (account_id, day, balance) VALUES (?, ?, ?)`, "acc1", lastWeek, 60)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		day  time.Time
+		want banking.DailyBalance
+	}{
+		{"a day with a balance", lastWeek, banking.DailyBalance{AccountID: "acc1", Day: lastWeek, Balance: 60}},
+		{"the day before", lastWeek.AddDate(0, 0, -1), banking.DailyBalance{AccountID: "acc1", Day: lastWeek, Balance: 60}},
+		{"the day after", lastWeek.AddDate(0, 0, 1), banking.DailyBalance{AccountID: "acc1", Day: today, Balance: 120}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daily, err := repo.FindDailyBalance(t.Context(), "acc1", tt.day)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, daily)
+		})
+	}
+
+	_, err = repo.FindDailyBalance(t.Context(), "acc1", today.AddDate(0, 0, 1))
+	require.ErrorIs(t, err, usecases.ErrNoDailyBalance)
+}
+
+func TestAccountRepository_BackfilledDailyBalances(t *testing.T) {
+	migrator, conn := newTestMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	_, err = migrator.Down(1)
+	require.NoError(t, err)
+	repo := NewAccountRepository(conn, SQLite, nil, time.Hour)
+
+	// The ledger of an account saved before daily balances were kept
+	today := banking.StartOfDay(time.Now())
+	_, err = conn.Exec(`INSERT INTO accounts (id, balance, currency, version) VALUES ('acc1', 120, 'EUR', 3)`)
+	require.NoError(t, err)
+	for _, entry := range []struct {
+		amount int
+		at     time.Time
+	}{
+		{100, today.AddDate(0, 0, -7).Add(time.Hour)},
+		{-30, today.AddDate(0, 0, -7).Add(23 * time.Hour)},
+		{50, today.AddDate(0, 0, -2)},
+	} {
+		_, err := conn.Exec(`INSERT INTO ledger_entries (journal_id, account_id, amount, currency, created_at) VALUES ('j', 'acc1', ?, 'EUR', ?)`,
+			entry.amount, entry.at.UTC())
+		require.NoError(t, err)
+	}
+	_, err = conn.Exec(`INSERT INTO daily_balances (account_id, day, balance) VALUES (?, ?, ?)`, "acc1", today.AddDate(0, 0, -2), 120)
+	require.NoError(t, err)
+
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	daily, err := repo.FindDailyBalance(t.Context(), "acc1", today.AddDate(0, 0, -8))
+	require.NoError(t, err)
+	assert.Equal(t, banking.DailyBalance{AccountID: "acc1", Day: today.AddDate(0, 0, -7), Balance: 70}, daily)
+
+	daily, err = repo.FindDailyBalance(t.Context(), "acc1", today.AddDate(0, 0, -6))
+	require.NoError(t, err)
+	assert.Equal(t, banking.DailyBalance{AccountID: "acc1", Day: today.AddDate(0, 0, -2), Balance: 120}, daily, "a day already kept")
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the flavour of SQL a database speaks. Queries are written with
+// ? placeholders, and rebound to the dialect when they run.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// adapt rewrites a query for the dialect. SQLite has no row locks, but
+// needs none: its transactions take the write lock of the whole database
+// as they begin.
+func (d Dialect) adapt(query string) string {
+	if d == SQLite {
+		query = strings.Replace(query, " FOR UPDATE", "", 1)
+	}
+	return d.Rebind(query)
+}
+
+// convert adapts the arguments of a query to the dialect. SQLite stores
+// timestamps as text, which only sorts in time order within one time zone.
+func (d Dialect) convert(args []any) []any {
+	if d != SQLite {
+		return args
+	}
+
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			arg = t.UTC()
+		}
+		converted[i] = arg
+	}
+	return converted
+}
+
+// Rebind replaces the ? placeholders of query with the ones of the dialect
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+// upsert completes an INSERT so a row already holding key gets the given columns updated instead
+func (d Dialect) upsert(insert string, key string, columns ...string) string {
+	updates := make([]string, len(columns))
+	if d == Postgres || d == SQLite {
+		for i, column := range columns {
+			updates[i] = column + " = EXCLUDED." + column
+		}
+		return insert + " ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	for i, column := range columns {
+		updates[i] = column + " = VALUES(" + column + ")"
+	}
+	return insert + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+// rebinder runs the queries of a repository in its dialect
+type rebinder struct {
+	querier querier
+	dialect Dialect
+}
+
+func (r rebinder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.querier.ExecContext(ctx, r.dialect.adapt(query), r.dialect.convert(args)...)
+}
+
+func (r rebinder) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.querier.QueryContext(ctx, r.dialect.adapt(query), r.dialect.convert(args)...)
+}
+
+func (r rebinder) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.querier.QueryRowContext(ctx, r.dialect.adapt(query), r.dialect.convert(args)...)
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := `SELECT id FROM accounts WHERE id > ? ORDER BY id LIMIT ?`
+
+	assert.Equal(t, query, MySQL.Rebind(query))
+	assert.Equal(t, `SELECT id FROM accounts WHERE id > $1 ORDER BY id LIMIT $2`, Postgres.Rebind(query))
+	assert.Equal(t, query, SQLite.Rebind(query))
+}
+
+func TestDialect_Adapt(t *testing.T) {
+	query := `SELECT id FROM accounts WHERE id = ? FOR UPDATE`
+
+	assert.Equal(t, query, MySQL.adapt(query))
+	assert.Equal(t, `SELECT id FROM accounts WHERE id = $1 FOR UPDATE`, Postgres.adapt(query))
+	assert.Equal(t, `SELECT id FROM accounts WHERE id = ?`, SQLite.adapt(query))
+}
+
+func TestDialect_Upsert(t *testing.T) {
+	insert := `INSERT INTO accounts (id, balance, status) VALUES (?, ?, ?)`
+
+	assert.Equal(t,
+		insert+` ON DUPLICATE KEY UPDATE balance = VALUES(balance), status = VALUES(status)`,
+		MySQL.upsert(insert, "id", "balance", "status"))
+	assert.Equal(t,
+		insert+` ON CONFLICT (id) DO UPDATE SET balance = EXCLUDED.balance, status = EXCLUDED.status`,
+		Postgres.upsert(insert, "id", "balance", "status"))
+	assert.Equal(t,
+		insert+` ON CONFLICT (id) DO UPDATE SET balance = EXCLUDED.balance, status = EXCLUDED.status`,
+		SQLite.upsert(insert, "id", "balance", "status"))
+}
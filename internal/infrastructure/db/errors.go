@@ -0,0 +1,78 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	errDuplicateKey    = 1062
+	errLockDeadlock    = 1213
+	errLockWaitTimeout = 1205
+)
+
+// Postgres SQLSTATEs for transactions that lost a race and can be run again
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateUniqueViolation      = "23505"
+)
+
+// translate reports MySQL deadlocks and lock wait timeouts, Postgres
+// serialization failures and deadlocks, and SQLite databases still locked
+// after the busy timeout as usecases.ErrTransactionConflict, so the use
+// cases know they can retry. Inserts of a key someone else inserted first
+// are reported as usecases.ErrConcurrentModification.
+func translate(err error) error {
+	if isConflict(err) {
+		return fmt.Errorf("%w: %w", usecases.ErrTransactionConflict, err)
+	}
+	if isDuplicate(err) {
+		return fmt.Errorf("%w: %w", usecases.ErrConcurrentModification, err)
+	}
+	return err
+}
+
+func isConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errLockDeadlock || mysqlErr.Number == errLockWaitTimeout
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// The extended result codes keep the primary one in their lowest byte
+		code := sqliteErr.Code() & 0xff
+		return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+	}
+	return false
+}
+
+func isDuplicate(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == errDuplicateKey
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == sqlStateUniqueViolation
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"deadlock", &mysql.MySQLError{Number: 1213}, usecases.ErrTransactionConflict},
+		{"lock wait timeout", &mysql.MySQLError{Number: 1205}, usecases.ErrTransactionConflict},
+		{"duplicate key", &mysql.MySQLError{Number: 1062}, usecases.ErrConcurrentModification},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, usecases.ErrTransactionConflict},
+		{"postgres deadlock", &pgconn.PgError{Code: "40P01"}, usecases.ErrTransactionConflict},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, usecases.ErrConcurrentModification},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, nil},
+		{"other error", errors.New("connection refused"), nil},
+		{"no error", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := translate(tt.err)
+			for _, retryable := range []error{usecases.ErrTransactionConflict, usecases.ErrConcurrentModification} {
+				assert.Equal(t, retryable == tt.want, errors.Is(err, retryable))
+			}
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+const findStreamQuery = `SELECT version FROM account_streams WHERE account_id = ?`
+const lockStreamQuery = findStreamQuery + ` FOR UPDATE`
+const insertStreamQuery = `INSERT INTO account_streams (account_id, version, sequence, fence_token, opened_at) VALUES (?, ?, ?, ?, ?)`
+const updateStreamQuery = `UPDATE account_streams SET version = ?, sequence = sequence + ?
+								WHERE account_id = ? AND version = ?`
+const updateFencedStreamQuery = `UPDATE account_streams SET version = ?, sequence = sequence + ?, fence_token = ?
+								WHERE account_id = ? AND version = ? AND fence_token <= ?`
+const findStreamFenceQuery = `SELECT fence_token FROM account_streams WHERE account_id = ?`
+const findStreamOpenedAtQuery = `SELECT opened_at FROM account_streams WHERE account_id = ?`
+const findStreamSequenceQuery = `SELECT sequence FROM account_streams WHERE account_id = ?`
+const listStreamsQuery = `SELECT account_id FROM account_streams WHERE account_id > ? ORDER BY account_id LIMIT ?`
+const saveAccountEventQuery = `INSERT INTO account_events (account_id, sequence, event_id, event_type, payload, occurred_at)
+								VALUES (?, ?, ?, ?, ?, ?)`
+const findAccountEventsQuery = `SELECT event_type, payload FROM account_events
+								WHERE account_id = ? AND sequence > ? AND occurred_at <= ? ORDER BY sequence`
+const saveSnapshotQuery = `INSERT INTO account_snapshots (account_id, sequence, owner, balance, currency, status, occurred_at)
+								VALUES (?, ?, ?, ?, ?, ?, ?)`
+const findSnapshotQuery = `SELECT sequence, owner, balance, currency, status FROM account_snapshots
+								WHERE account_id = ? AND occurred_at <= ? ORDER BY sequence DESC LIMIT 1`
+
+// ErrAccountStorageChanged is reported when a database holding accounts
+// stored one way is opened to store them the other way
+var ErrAccountStorageChanged = errors.New("accounts are stored the other way in this database")
+
+// endOfTime bounds the replay of the current state of an account, which
+// takes every event it has
+var endOfTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// EventSourcedAccountRepository stores accounts as the stream of the events
+// they raised, and rebuilds them by replaying it from their latest snapshot.
+// A snapshot is taken every snapshotEvery events, and when an account is
+// first saved with a state its events do not account for, such as an
+// imported one. Ledger entries, status changes and the outbox are written
+// as by AccountRepository, but accounts are not cached.
+type EventSourcedAccountRepository struct {
+	db            *sql.DB
+	dialect       Dialect
+	snapshotEvery int
+	unit          *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *EventSourcedAccountRepository) inTx(u *unit) AccountStore {
+	return r.bind(u)
+}
+
+func (r *EventSourcedAccountRepository) bind(u *unit) *EventSourcedAccountRepository {
+	bound := *r
+	bound.unit = u
+	return &bound
+}
+
+// Find locks the stream of the account when locking pessimistically within
+// a transaction, so no event is appended to it until the end of it
+func (r *EventSourcedAccountRepository) Find(ctx context.Context, id string) (*banking.Account, error) {
+	if r.unit != nil && r.unit.locking == Pessimistic {
+		return r.load(ctx, lockStreamQuery, id)
+	}
+	return r.load(ctx, findStreamQuery, id)
+}
+
+// FindAt rebuilds the account as it was at the given time, from the events
+// that had happened by then. It has no version, as it is not meant to be saved.
+func (r *EventSourcedAccountRepository) FindAt(ctx context.Context, id string, at time.Time) (*banking.Account, error) {
+	return r.replay(ctx, id, at)
+}
+
+func (r *EventSourcedAccountRepository) load(ctx context.Context, query, id string) (*banking.Account, error) {
+	var version int
+	err := r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, query, id).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, banking.ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+
+	account, err := r.replay(ctx, id, endOfTime)
+	if err != nil {
+		return nil, err
+	}
+	account.Version = version
+	return account, nil
+}
+
+// replay rebuilds an account from its latest snapshot taken by at, and the
+// events after it that happened by at
+func (r *EventSourcedAccountRepository) replay(ctx context.Context, id string, at time.Time) (*banking.Account, error) {
+	q := r.unit.querier(r.db, r.dialect)
+
+	account := &banking.Account{}
+	sequence := -1
+	row := q.QueryRowContext(ctx, findSnapshotQuery, id, at)
+	switch err := row.Scan(&sequence, &account.Owner, &account.Balance, &account.Currency, &account.Status); {
+	case err == nil:
+		account.ID = id
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, translate(err)
+	}
+
+	rows, err := q.QueryContext(ctx, findAccountEventsQuery, id, sequence, at)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var events []banking.Event
+	for rows.Next() {
+		var eventType string
+		var payload []byte
+		if err := rows.Scan(&eventType, &payload); err != nil {
+			return nil, err
+		}
+		event, err := decodeAccountEvent(eventType, payload)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translate(err)
+	}
+
+	if account.ID == "" && len(events) == 0 {
+		return nil, banking.ErrAccountNotFound
+	}
+	banking.Replay(account, events...)
+	return account, nil
+}
+
+// List returns up to limit accounts ordered by ID, starting after the given one
+func (r *EventSourcedAccountRepository) List(ctx context.Context, after string, limit int) ([]*banking.Account, error) {
+	rows, err := r.unit.querier(r.db, r.dialect).QueryContext(ctx, listStreamsQuery, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read all the IDs before replaying, as a transaction runs one query at a time
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	accounts := make([]*banking.Account, 0, len(ids))
+	for _, id := range ids {
+		account, err := r.load(ctx, findStreamQuery, id)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// Save appends the pending events of the account to its stream, writes its
+// pending ledger entries and status changes, and increments its version. It
+// fails with usecases.ErrConcurrentModification if the account was saved by
+// someone else since it was read. Outside a transaction, it runs in one of
+// its own.
+func (r *EventSourcedAccountRepository) Save(ctx context.Context, account *banking.Account) error {
+	return r.save(ctx, account, 0)
+}
+
+// SaveFenced saves the account as Save does, recording the fencing token it
+// is saved under with its stream. It fails with usecases.ErrLockLost if the
+// account was saved under a greater token since.
+func (r *EventSourcedAccountRepository) SaveFenced(ctx context.Context, account *banking.Account, token int64) error {
+	return r.save(ctx, account, token)
+}
+
+// save fences the save with token, unless it is 0
+func (r *EventSourcedAccountRepository) save(ctx context.Context, account *banking.Account, token int64) error {
+	if r.unit == nil {
+		return withinTx(ctx, r.db, Pessimistic, func(u *unit) error {
+			return r.bind(u).save(ctx, account, token)
+		})
+	}
+
+	q := r.unit.querier(r.db, r.dialect)
+	events := account.PendingEvents()
+	sequence, err := r.appendToStream(ctx, account, len(events), token)
+	if err != nil {
+		return translate(err)
+	}
+
+	previous := sequence - len(events)
+	for i, event := range events {
+		message, err := usecases.NewOutboxMessage(event)
+		if err != nil {
+			return err
+		}
+		args := []any{account.ID, previous + i + 1, message.EventID, message.EventType, string(message.Payload), message.OccurredAt}
+		if _, err := q.ExecContext(ctx, saveAccountEventQuery, args...); err != nil {
+			return translate(err)
+		}
+	}
+
+	opened := len(events) > 0 && events[0].EventType() == banking.EventAccountOpened
+	if (account.Version == 0 && !opened) || previous/r.snapshotEvery != sequence/r.snapshotEvery {
+		if err := r.saveSnapshot(ctx, account, sequence, events); err != nil {
+			return translate(err)
+		}
+	}
+
+	if err := saveHistory(ctx, q, r.dialect, account); err != nil {
+		return translate(err)
+	}
+	account.Version++
+	return nil
+}
+
+// appendToStream makes room for n events at the end of the stream of the
+// account, creating it on the first save, and returns the sequence of the
+// last of them. Like AccountRepository, it fences the stream with token
+// unless it is 0.
+func (r *EventSourcedAccountRepository) appendToStream(ctx context.Context, account *banking.Account, n int, token int64) (int, error) {
+	q := r.unit.querier(r.db, r.dialect)
+	version := account.Version + 1
+	if account.Version == 0 {
+		_, err := q.ExecContext(ctx, insertStreamQuery, account.ID, version, n, token, time.Now().UTC())
+		return n, err
+	}
+
+	query := updateStreamQuery
+	args := []any{version, n, account.ID, account.Version}
+	if token > 0 {
+		query = updateFencedStreamQuery
+		args = []any{version, n, token, account.ID, account.Version, token}
+	}
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return 0, err
+	} else if updated == 0 {
+		return 0, notUpdated(ctx, q, findStreamFenceQuery, account.ID, token)
+	}
+
+	var sequence int
+	err = q.QueryRowContext(ctx, findStreamSequenceQuery, account.ID).Scan(&sequence)
+	return sequence, err
+}
+
+// saveSnapshot records the state of the account once the event at sequence
+// was applied, as of when that event happened
+func (r *EventSourcedAccountRepository) saveSnapshot(ctx context.Context, account *banking.Account, sequence int, events []banking.Event) error {
+	occurredAt := time.Now().UTC()
+	if len(events) > 0 {
+		occurredAt = events[len(events)-1].Metadata().OccurredAt
+	}
+
+	args := []any{account.ID, sequence, account.Owner, account.Balance, account.Currency, account.Status, occurredAt}
+	_, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, saveSnapshotQuery, args...)
+	return err
+}
+
+// FindLedgerEntries returns the ledger history of an account in the order it was written
+func (r *EventSourcedAccountRepository) FindLedgerEntries(ctx context.Context, accountID string) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesQuery, accountID)
+}
+
+// FindLedgerEntriesSince returns the ledger entries of an account written at or after since
+func (r *EventSourcedAccountRepository) FindLedgerEntriesSince(ctx context.Context, accountID string, since time.Time) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesSinceQuery, accountID, since)
+}
+
+// FindLedgerEntriesBetween returns the ledger entries of an account written within [from, to)
+func (r *EventSourcedAccountRepository) FindLedgerEntriesBetween(ctx context.Context, accountID string, from, to time.Time) ([]banking.LedgerEntry, error) {
+	return queryLedgerEntries(ctx, r.unit.querier(r.db, r.dialect), findLedgerEntriesBetweenQuery, accountID, from, to)
+}
+
+// FindDailyBalance returns the balance of the account at the end of the first day on or after day it was saved
+func (r *EventSourcedAccountRepository) FindDailyBalance(ctx context.Context, accountID string, day time.Time) (banking.DailyBalance, error) {
+	return findDailyBalance(ctx, r.unit.querier(r.db, r.dialect), accountID, day)
+}
+
+// FindOpenedAt returns when the stream of the account was created, or the zero time if that was not recorded
+func (r *EventSourcedAccountRepository) FindOpenedAt(ctx context.Context, accountID string) (time.Time, error) {
+	return findOpenedAt(ctx, r.unit.querier(r.db, r.dialect), findStreamOpenedAtQuery, accountID)
+}
+
+// decodeAccountEvent reads back an event of an account stream
+func decodeAccountEvent(eventType string, payload []byte) (banking.Event, error) {
+	switch eventType {
+	case banking.EventAccountOpened:
+		return decodeEvent[banking.AccountOpened](payload)
+	case banking.EventAccountFrozen, banking.EventAccountUnfrozen, banking.EventAccountClosed:
+		return decodeEvent[banking.AccountStatusChanged](payload)
+	case banking.EventAccountCredited:
+		return decodeEvent[banking.AccountCredited](payload)
+	case banking.EventAccountDebited:
+		return decodeEvent[banking.AccountDebited](payload)
+	}
+	return nil, fmt.Errorf("unknown account event type %q", eventType)
+}
+
+func decodeEvent[E banking.Event](payload []byte) (banking.Event, error) {
+	var event E
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// CheckAccountStorage fails with ErrAccountStorageChanged unless the
+// accounts of the database are stored as events says. Accounts stored as
+// their state have no stream to be replayed from, and the state of accounts
+// stored as events is never written, so neither can be read the other way.
+func CheckAccountStorage(ctx context.Context, db *sql.DB, dialect Dialect, events bool) error {
+	table, other := "account_streams", "accounts"
+	if events {
+		table, other = other, table
+	}
+
+	var found int
+	err := rebinder{querier: db, dialect: dialect}.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found > 0 {
+		return fmt.Errorf("%w: %d accounts are in %s rather than %s", ErrAccountStorageChanged, found, table, other)
+	}
+	return nil
+}
+
+// NewEventSourcedAccountRepository snapshots accounts every snapshotEvery
+// events, which must be at least 1
+func NewEventSourcedAccountRepository(db *sql.DB, dialect Dialect, snapshotEvery int) *EventSourcedAccountRepository {
+	return &EventSourcedAccountRepository{db: db, dialect: dialect, snapshotEvery: snapshotEvery}
+}
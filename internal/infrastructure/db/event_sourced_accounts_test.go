@@ -0,0 +1,141 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEventSourcedRepository(t *testing.T, snapshotEvery int) (*EventSourcedAccountRepository, func(table string) int) {
+	t.Helper()
+	migrator, conn := newTestMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+
+	count := func(table string) int {
+		var n int
+		require.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+	return NewEventSourcedAccountRepository(conn, SQLite, snapshotEvery), count
+}
+
+func TestEventSourcedAccountRepository_Replay(t *testing.T) {
+	repo, count := newTestEventSourcedRepository(t, 3)
+
+	account, err := banking.OpenAccount("alice", "EUR", "teller")
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(t.Context(), account))
+	assert.Zero(t, count("account_snapshots"), "an opened account is rebuilt from its events")
+
+	for range 4 {
+		require.NoError(t, banking.Deposit(account, 10))
+	}
+	require.NoError(t, banking.Withdraw(account, 5))
+	require.NoError(t, banking.Freeze(account, "audit", "compliance"))
+	require.NoError(t, repo.Save(t.Context(), account))
+
+	assert.Equal(t, 7, count("account_events"))
+	assert.Equal(t, 1, count("account_snapshots"))
+
+	found, err := repo.Find(t.Context(), account.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", found.Owner)
+	assert.Equal(t, "EUR", found.Currency)
+	assert.Equal(t, 35, found.Balance)
+	assert.Equal(t, banking.AccountFrozen, found.Status)
+	assert.Equal(t, 2, found.Version)
+	assert.Empty(t, found.PendingEvents())
+}
+
+func TestEventSourcedAccountRepository_ImportedAccount(t *testing.T) {
+	repo, count := newTestEventSourcedRepository(t, 100)
+
+	account := &banking.Account{ID: "acc1", Owner: "bob", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+	require.NoError(t, repo.Save(t.Context(), account))
+	assert.Equal(t, 1, count("account_snapshots"), "a state without events is snapshotted")
+
+	require.NoError(t, banking.Deposit(account, 20))
+	require.NoError(t, repo.Save(t.Context(), account))
+
+	found, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	assert.Equal(t, 120, found.Balance)
+
+	_, err = repo.Find(t.Context(), "missing")
+	require.ErrorIs(t, err, banking.ErrAccountNotFound)
+}
+
+func TestEventSourcedAccountRepository_ConcurrentModification(t *testing.T) {
+	repo, _ := newTestEventSourcedRepository(t, 100)
+
+	account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+	require.NoError(t, repo.Save(t.Context(), account))
+
+	stale, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+
+	require.NoError(t, banking.Deposit(account, 10))
+	require.NoError(t, repo.Save(t.Context(), account))
+
+	require.NoError(t, banking.Withdraw(stale, 10))
+	require.ErrorIs(t, repo.Save(t.Context(), stale), usecases.ErrConcurrentModification)
+
+	found, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	assert.Equal(t, 110, found.Balance)
+}
+
+func TestEventSourcedAccountRepository_FindAt(t *testing.T) {
+	repo, _ := newTestEventSourcedRepository(t, 2)
+
+	account, err := banking.OpenAccount("alice", "EUR", "teller")
+	require.NoError(t, err)
+	require.NoError(t, repo.Save(t.Context(), account))
+	opened := time.Now().UTC()
+
+	var balances []time.Time
+	for range 5 {
+		time.Sleep(time.Millisecond)
+		require.NoError(t, banking.Deposit(account, 10))
+		require.NoError(t, repo.Save(t.Context(), account))
+		balances = append(balances, time.Now().UTC())
+	}
+
+	_, err = repo.FindAt(t.Context(), account.ID, opened.Add(-time.Hour))
+	require.ErrorIs(t, err, banking.ErrAccountNotFound, "the account did not exist yet")
+
+	past, err := repo.FindAt(t.Context(), account.ID, opened)
+	require.NoError(t, err)
+	assert.Zero(t, past.Balance)
+	assert.Equal(t, banking.AccountActive, past.Status)
+
+	for i, at := range balances {
+		past, err := repo.FindAt(t.Context(), account.ID, at)
+		require.NoError(t, err)
+		assert.Equal(t, 10*(i+1), past.Balance)
+	}
+}
+
+func TestCheckAccountStorage(t *testing.T) {
+	migrator, conn := newTestMigrator(t)
+	_, err := migrator.Up()
+	require.NoError(t, err)
+	require.NoError(t, CheckAccountStorage(t.Context(), conn, SQLite, true), "an empty database takes either")
+	require.NoError(t, CheckAccountStorage(t.Context(), conn, SQLite, false))
+
+	account := &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+	require.NoError(t, NewAccountRepository(conn, SQLite, nil, time.Hour).Save(t.Context(), account))
+	require.NoError(t, CheckAccountStorage(t.Context(), conn, SQLite, false))
+	require.ErrorIs(t, CheckAccountStorage(t.Context(), conn, SQLite, true), ErrAccountStorageChanged)
+
+	events, _ := newTestEventSourcedRepository(t, 3)
+	account = &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive}
+	require.NoError(t, events.Save(t.Context(), account))
+	require.NoError(t, CheckAccountStorage(t.Context(), events.db, SQLite, true))
+	require.ErrorIs(t, CheckAccountStorage(t.Context(), events.db, SQLite, false), ErrAccountStorageChanged)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+const findRateQuery = `SELECT rate, spread_bps, updated_at FROM fx_rates WHERE base = ? AND quote = ?`
+const saveConversionQuery = `INSERT INTO fx_conversions (id, source_amount, source_currency, converted_amount, 
+								converted_currency, rate, spread_bps, rate_as_of, spread, rounding) 
+								VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// decimals kept when storing spreads and rounding remainders, in minor units
+const fractionDecimals = 12
+
+// FXRepository quotes rates from the fx_rates table maintained by treasury,
+// so transfers can be converted without reaching an external provider, and
+// records the conversions applied.
+type FXRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	unit    *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *FXRepository) inTx(u *unit) *FXRepository {
+	return &FXRepository{db: r.db, dialect: r.dialect, unit: u}
+}
+
+func (r *FXRepository) Rate(ctx context.Context, base, quote string) (banking.Rate, error) {
+	var value string
+	rate := banking.Rate{Base: base, Quote: quote}
+	err := r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, findRateQuery, base, quote).Scan(&value, &rate.SpreadBps, &rate.AsOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return banking.Rate{}, fmt.Errorf("fx rate %s/%s: %w", base, quote, usecases.ErrRateUnavailable)
+	}
+	if err != nil {
+		return banking.Rate{}, fmt.Errorf("fx rate %s/%s: %w", base, quote, err)
+	}
+
+	var ok bool
+	if rate.Value, ok = new(big.Rat).SetString(value); !ok {
+		return banking.Rate{}, fmt.Errorf("fx rate %s/%s: malformed rate %q", base, quote, value)
+	}
+	return rate, nil
+}
+
+func (r *FXRepository) SaveConversion(ctx context.Context, conversion banking.Conversion) error {
+	args := []any{
+		conversion.ID,
+		conversion.Source.Amount,
+		conversion.Source.Currency,
+		conversion.Converted.Amount,
+		conversion.Converted.Currency,
+		conversion.Rate.Value.FloatString(fractionDecimals),
+		conversion.Rate.SpreadBps,
+		conversion.Rate.AsOf,
+		conversion.Spread.FloatString(fractionDecimals),
+		conversion.Rounding.FloatString(fractionDecimals),
+	}
+
+	_, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, saveConversionQuery, args...)
+	return translate(err)
+}
+
+func NewFXRepository(db *sql.DB, dialect Dialect) *FXRepository {
+	return &FXRepository{db: db, dialect: dialect}
+}
@@ -0,0 +1,29 @@
+package db
+
+// txHooks holds work that must wait until a transaction has ended, such as
+// cache updates that would otherwise expose uncommitted data
+type txHooks struct {
+	onCommit   []func()
+	onRollback []func()
+}
+
+func (h *txHooks) afterCommit(hook func()) {
+	h.onCommit = append(h.onCommit, hook)
+}
+
+func (h *txHooks) afterRollback(hook func()) {
+	h.onRollback = append(h.onRollback, hook)
+}
+
+// ended runs the hooks registered for the way the transaction ended and forgets the others
+func (h *txHooks) ended(committed bool) {
+	hooks := h.onRollback
+	if committed {
+		hooks = h.onCommit
+	}
+	h.onCommit, h.onRollback = nil, nil
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxHooks(t *testing.T) {
+	var ran []string
+	var committed, rolledBack txHooks
+
+	committed.afterCommit(func() { ran = append(ran, "commit 1") })
+	committed.afterCommit(func() { ran = append(ran, "commit 2") })
+	committed.afterRollback(func() { ran = append(ran, "rollback 1") })
+	rolledBack.afterCommit(func() { ran = append(ran, "commit 3") })
+	rolledBack.afterRollback(func() { ran = append(ran, "rollback 2") })
+
+	committed.ended(true)
+	rolledBack.ended(false)
+	assert.Equal(t, []string{"commit 1", "commit 2", "rollback 2"}, ran)
+
+	// Hooks run once
+	committed.ended(true)
+	assert.Len(t, ran, 3)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+const findIdempotencyKeyQuery = `SELECT idempotency_key, request_hash, transfer_id, created_at FROM idempotency_keys 
+								WHERE idempotency_key = ? FOR UPDATE`
+const saveIdempotencyKeyQuery = `INSERT INTO idempotency_keys (idempotency_key, request_hash, transfer_id, created_at) 
+								VALUES (?, ?, ?, ?)`
+
+type IdempotencyRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	unit    *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *IdempotencyRepository) inTx(u *unit) *IdempotencyRepository {
+	return &IdempotencyRepository{db: r.db, dialect: r.dialect, unit: u}
+}
+
+func (r *IdempotencyRepository) FindIdempotencyKey(ctx context.Context, key string) (*usecases.IdempotencyKey, error) {
+	row := r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, findIdempotencyKeyQuery, key)
+
+	var idempotencyKey usecases.IdempotencyKey
+	err := row.Scan(&idempotencyKey.Key, &idempotencyKey.RequestHash, &idempotencyKey.TransferID, &idempotencyKey.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &idempotencyKey, nil
+}
+
+func (r *IdempotencyRepository) SaveIdempotencyKey(ctx context.Context, key usecases.IdempotencyKey) error {
+	args := []any{key.Key, key.RequestHash, key.TransferID, key.CreatedAt}
+	_, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, saveIdempotencyKeyQuery, args...)
+	return translate(err)
+}
+
+func NewIdempotencyRepository(db *sql.DB, dialect Dialect) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db, dialect: dialect}
+}
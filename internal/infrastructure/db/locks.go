@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/redis/go-redis/v9"
+)
+
+const lockKeyPrefix = "lock:"
+
+// fencingKey holds the counter every fencing token is drawn from
+const fencingKey = "lock-fencing-token"
+
+const maxLockBackoff = 100 * time.Millisecond
+
+// renewScript extends the lease of all the keys, only if they are all still held with the given token
+var renewScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call("GET", key) ~= ARGV[1] then
+		return 0
+	end
+end
+for _, key in ipairs(KEYS) do
+	redis.call("PEXPIRE", key, ARGV[2])
+end
+return 1
+`)
+
+// releaseScript deletes the keys still held with the given token, leaving those taken over by someone else
+var releaseScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call("GET", key) == ARGV[1] then
+		redis.call("DEL", key)
+	end
+end
+return 1
+`)
+
+// RedisLockManager implements usecases.LockManager on a single Redis
+// instance. Each key is a SET NX entry holding the lock's fencing token, and
+// expires after ttl unless the holder renews it.
+type RedisLockManager struct {
+	redis *redis.Client
+	ttl   time.Duration
+	wait  time.Duration
+}
+
+// Acquire locks the keys in sorted order, waiting up to the manager's wait
+// time for the ones held by someone else, or until ctx is done. The lease
+// is renewed in the background until the lock is released.
+func (m *RedisLockManager) Acquire(ctx context.Context, keys ...string) (usecases.Lock, error) {
+	token, err := m.redis.Incr(ctx, fencingKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &redisLock{
+		redis: m.redis,
+		token: token,
+		ttl:   m.ttl,
+		stop:  make(chan struct{}),
+	}
+
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	deadline := time.Now().Add(m.wait)
+	for _, key := range slices.Compact(keys) {
+		if err := m.acquire(ctx, lock, lockKeyPrefix+key, deadline); err != nil {
+			lock.Release(context.WithoutCancel(ctx))
+			return nil, err
+		}
+	}
+
+	go lock.renew()
+	return lock, nil
+}
+
+func (m *RedisLockManager) acquire(ctx context.Context, lock *redisLock, key string, deadline time.Time) error {
+	for backoff := time.Millisecond; ; backoff = min(2*backoff, maxLockBackoff) {
+		acquired, err := m.redis.SetNX(ctx, key, lock.value(), m.ttl).Result()
+		if err != nil {
+			return err
+		}
+		if acquired {
+			lock.keys = append(lock.keys, key)
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return usecases.ErrLockNotAcquired
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+type redisLock struct {
+	redis *redis.Client
+	keys  []string
+	token int64
+	ttl   time.Duration
+	once  sync.Once
+	stop  chan struct{}
+}
+
+func (l *redisLock) Token() int64 {
+	return l.token
+}
+
+func (l *redisLock) Refresh(ctx context.Context) error {
+	held, err := renewScript.Run(ctx, l.redis, l.keys, l.value(), l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if held == 0 {
+		return usecases.ErrLockLost
+	}
+	return nil
+}
+
+func (l *redisLock) Release(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	if len(l.keys) == 0 {
+		return nil
+	}
+	return releaseScript.Run(ctx, l.redis, l.keys, l.value()).Err()
+}
+
+// renew refreshes the lease three times per TTL until the lock is released
+// or lost. A failed refresh, such as a Redis timeout, is retried on the next
+// tick while the lease may still be alive. It outlives the request that
+// acquired the lock, so it has a context of its own, bounded by the tick.
+func (l *redisLock) renew() {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+			err := l.Refresh(ctx)
+			cancel()
+			if errors.Is(err, usecases.ErrLockLost) {
+				return
+			}
+		}
+	}
+}
+
+func (l *redisLock) value() string {
+	return strconv.FormatInt(l.token, 10)
+}
+
+func NewRedisLockManager(redis *redis.Client, ttl, wait time.Duration) *RedisLockManager {
+	return &RedisLockManager{
+		redis: redis,
+		ttl:   ttl,
+		wait:  wait,
+	}
+}
@@ -0,0 +1,445 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	// ErrChecksumMismatch means a migration was edited after it was applied
+	ErrChecksumMismatch = errors.New("migration changed since it was applied")
+	// ErrUnknownMigration means the database was migrated by a newer binary
+	ErrUnknownMigration = errors.New("database has a migration this binary does not know")
+)
+
+// versionTables keep the migrations applied to a database, in each dialect
+var versionTables = map[Dialect]string{
+	MySQL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at DATETIME(6) NOT NULL
+	)`,
+	Postgres: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	SQLite: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+}
+
+// migrationLocks serialize the migrators of a database, so two deploys
+// cannot apply the same migration. They are held by the connection a
+// migrator runs on, across the implicit commits of MySQL schema changes.
+// SQLite needs none, as its schema changes are transactional: a migrator
+// racing another fails on the version table rather than applying a
+// migration twice.
+var migrationLocks = map[Dialect]struct{ lock, unlock string }{
+	MySQL:    {`SELECT GET_LOCK('schema_migrations', -1)`, `SELECT RELEASE_LOCK('schema_migrations')`},
+	Postgres: {`SELECT pg_advisory_lock(hashtext('schema_migrations'))`, `SELECT pg_advisory_unlock(hashtext('schema_migrations'))`},
+}
+
+const findAppliedMigrationsQuery = `SELECT version, checksum FROM schema_migrations ORDER BY version`
+const saveMigrationQuery = `INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+const deleteMigrationQuery = `DELETE FROM schema_migrations WHERE version = ?`
+
+// Migration is one versioned change to the schema, read from the
+// NNNN_name.up.sql and NNNN_name.down.sql files of its dialect
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of Up, recorded when the migration is applied
+	Checksum string
+}
+
+// MigrationStatus tells whether a migration has been applied to the database
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+// Migrator applies the embedded migrations of its dialect to a database.
+// Each migration runs in a transaction with the update of the version
+// table, although MySQL commits schema changes as they run.
+type Migrator struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+// Up applies every migration not applied yet, in version order, and returns them
+func (m *Migrator) Up() ([]Migration, error) {
+	conn, unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.verify(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.run(conn, migration.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.dialect.adapt(saveMigrationQuery),
+				m.dialect.convert([]any{migration.Version, migration.Name, migration.Checksum, time.Now()})...)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps migrations applied, or all of them if steps
+// is negative, newest first, and returns them
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	conn, unlock, err := m.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.verify(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range slices.Backward(m.migrations) {
+		if len(done) == steps {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.run(conn, migration.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(m.dialect.adapt(deleteMigrationQuery), migration.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status lists every migration, and whether it has been applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	applied, err := m.verify(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		_, ok := applied[migration.Version]
+		statuses[i] = MigrationStatus{Migration: migration, Applied: ok}
+	}
+	return statuses, nil
+}
+
+// lock takes the migration lock of the database on a connection of its
+// own, and returns it with the function releasing both
+func (m *Migrator) lock() (*sql.Conn, func(), error) {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lock, ok := migrationLocks[m.dialect]
+	if !ok {
+		return conn, func() { conn.Close() }, nil
+	}
+	if _, err := conn.ExecContext(ctx, lock.lock); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, func() {
+		conn.ExecContext(ctx, lock.unlock)
+		conn.Close()
+	}, nil
+}
+
+// verify creates the version table if needed, then checks every migration
+// applied is still the one embedded, and returns their checksums by version
+func (m *Migrator) verify(conn *sql.Conn) (map[int]string, error) {
+	ctx := context.Background()
+	if _, err := conn.ExecContext(ctx, versionTables[m.dialect]); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, findAppliedMigrationsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for version, checksum := range applied {
+		i := slices.IndexFunc(m.migrations, func(migration Migration) bool { return migration.Version == version })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: version %d", ErrUnknownMigration, version)
+		}
+		if migration := m.migrations[i]; migration.Checksum != checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return applied, nil
+}
+
+// run executes the statements of a migration and records it, in one transaction on conn
+func (m *Migrator) run(conn *sql.Conn, script string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements(script, m.dialect) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// statements splits a script on the semicolons ending its statements.
+// Semicolons inside quotes, comments, Postgres dollar quotes and the
+// BEGIN ... END bodies of triggers and routines do not end a statement,
+// and chunks holding only comments are dropped.
+func statements(script string, dialect Dialect) []string {
+	var result []string
+	start, depth := 0, 0
+	// routine is set once the statement creates a trigger or routine,
+	// whose body may hold semicolons between BEGIN and END
+	routine, code := false, false
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, dialect == MySQL)
+			code = true
+		case strings.HasPrefix(script[i:], "--") || (c == '#' && dialect == MySQL):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(script)
+			}
+		case strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(script)
+			}
+		case c == '$' && dialect == Postgres && dollarTag(script[i:]) != "":
+			tag := dollarTag(script[i:])
+			if end := strings.Index(script[i+len(tag):], tag); end >= 0 {
+				i += len(tag) + end + len(tag)
+			} else {
+				i = len(script)
+			}
+			code = true
+		case isWordByte(c) && !isDigit(c):
+			word := nextWord(script[i:])
+			i += len(word)
+			code = true
+			switch strings.ToUpper(word) {
+			case "TRIGGER", "FUNCTION", "PROCEDURE":
+				routine = true
+			case "BEGIN", "CASE":
+				if routine {
+					depth++
+				}
+			case "END":
+				if !routine {
+					break
+				}
+				// END IF, END LOOP and the like close blocks that BEGIN did not open
+				rest := strings.TrimLeft(script[i:], " \t\r\n")
+				switch next := nextWord(rest); strings.ToUpper(next) {
+				case "IF", "LOOP", "WHILE", "REPEAT":
+					i = len(script) - len(rest) + len(next)
+				case "CASE":
+					i = len(script) - len(rest) + len(next)
+					depth--
+				default:
+					depth--
+				}
+			}
+		case c == ';' && depth <= 0:
+			if code {
+				result = append(result, strings.TrimSpace(script[start:i]))
+			}
+			i++
+			start, depth, routine, code = i, 0, false, false
+		default:
+			if !strings.ContainsRune(" \t\r\n", rune(c)) {
+				code = true
+			}
+			i++
+		}
+	}
+	if code {
+		result = append(result, strings.TrimSpace(script[start:]))
+	}
+	return result
+}
+
+// skipQuoted returns the index just past the quoted string or identifier
+// starting at i. Backslashes escape quotes only in MySQL; a doubled quote
+// reads as two adjacent strings, which splits the same way.
+func skipQuoted(script string, i int, backslashes bool) int {
+	quote := script[i]
+	for i++; i < len(script); i++ {
+		switch script[i] {
+		case '\\':
+			if backslashes {
+				i++
+			}
+		case quote:
+			return i + 1
+		}
+	}
+	return len(script)
+}
+
+// dollarTag returns the $tag$ opening a Postgres dollar-quoted string at
+// the start of s, or "" if there is none
+func dollarTag(s string) string {
+	name := nextWord(s[1:])
+	if name != "" && isDigit(name[0]) || !strings.HasPrefix(s[1+len(name):], "$") {
+		return ""
+	}
+	return s[:len(name)+2]
+}
+
+// nextWord returns the identifier or keyword at the start of s
+func nextWord(s string) string {
+	end := 0
+	for end < len(s) && isWordByte(s[end]) {
+		end++
+	}
+	return s[:end]
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// loadMigrations reads the migrations of a dialect from files, in version order
+func loadMigrations(files fs.FS, dialect Dialect) ([]Migration, error) {
+	dir := path.Join("migrations", string(dialect))
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		number, name, found := strings.Cut(name, "_")
+		version, err := strconv.Atoi(number)
+		if !ok || !found || err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration file %s: want NNNN_name.up.sql or NNNN_name.down.sql", entry.Name())
+		}
+
+		content, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, migration.Name, name)
+		}
+		if direction == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up, migration.Checksum = string(content), hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+func NewMigrator(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
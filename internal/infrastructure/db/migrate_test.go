@@ -0,0 +1,234 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	t.Helper()
+	conn, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "banking.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	migrator, err := NewMigrator(conn, SQLite)
+	require.NoError(t, err)
+	return migrator, conn
+}
+
+func tableExists(t *testing.T, conn *sql.DB, table string) bool {
+	t.Helper()
+	var count int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	require.NoError(t, err)
+	return count > 0
+}
+
+func TestMigrator_UpAndDown(t *testing.T) {
+	migrator, conn := newTestMigrator(t)
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations))
+	assert.True(t, tableExists(t, conn, "accounts"))
+	assert.True(t, tableExists(t, conn, "fx_rates"))
+
+	applied, err = migrator.Up()
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, migrator.migrations[len(migrator.migrations)-1], reverted[0])
+	assert.True(t, tableExists(t, conn, "accounts"))
+
+	statuses, err := migrator.Status()
+	require.NoError(t, err)
+	last := statuses[len(statuses)-1]
+	assert.False(t, last.Applied)
+	assert.True(t, statuses[0].Applied)
+
+	_, err = migrator.Down(-1)
+	require.NoError(t, err)
+	assert.False(t, tableExists(t, conn, "accounts"))
+}
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banking.db")
+	var wg sync.WaitGroup
+	for range 4 {
+		conn, err := OpenSQLite(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		migrator, err := NewMigrator(conn, SQLite)
+		require.NoError(t, err)
+
+		// A migrator may lose the race, but no migration runs twice
+		wg.Go(func() { migrator.Up() })
+	}
+	wg.Wait()
+
+	conn, err := OpenSQLite(path)
+	require.NoError(t, err)
+	defer conn.Close()
+	migrator, err := NewMigrator(conn, SQLite)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	var versions, distinct int
+	require.NoError(t, conn.QueryRow(`SELECT COUNT(*), COUNT(DISTINCT version) FROM schema_migrations`).Scan(&versions, &distinct))
+	assert.Equal(t, len(migrator.migrations), versions)
+	assert.Equal(t, versions, distinct)
+}
+
+func TestMigrator_AdoptsLegacyAccounts(t *testing.T) {
+	migrator, conn := newTestMigrator(t)
+
+	// The accounts table databases had before migrations
+	_, err := conn.Exec(`CREATE TABLE accounts (id VARCHAR(255) PRIMARY KEY, balance BIGINT NOT NULL)`)
+	require.NoError(t, err)
+	_, err = conn.Exec(`INSERT INTO accounts (id, balance) VALUES ('acc1', 100)`)
+	require.NoError(t, err)
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations))
+
+	repo := NewAccountRepository(conn, SQLite, nil, time.Hour)
+	account, err := repo.Find(t.Context(), "acc1")
+	require.NoError(t, err)
+	assert.Equal(t, &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR", Status: banking.AccountActive, Version: 1}, account)
+
+	require.NoError(t, banking.Deposit(account, 20))
+	require.NoError(t, repo.Save(t.Context(), account))
+}
+
+func TestMigrator_Verifies(t *testing.T) {
+	t.Run("edited migration", func(t *testing.T) {
+		migrator, conn := newTestMigrator(t)
+		_, err := migrator.Up()
+		require.NoError(t, err)
+
+		_, err = conn.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`)
+		require.NoError(t, err)
+
+		_, err = migrator.Up()
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		_, err = migrator.Down(1)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("unknown migration", func(t *testing.T) {
+		migrator, conn := newTestMigrator(t)
+		_, err := migrator.Up()
+		require.NoError(t, err)
+
+		_, err = conn.Exec(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (9999, 'future', '', '')`)
+		require.NoError(t, err)
+
+		_, err = migrator.Status()
+		assert.ErrorIs(t, err, ErrUnknownMigration)
+	})
+}
+
+func TestLoadMigrations(t *testing.T) {
+	// Every dialect has the same migrations
+	var versions [][]int
+	for _, dialect := range []Dialect{MySQL, Postgres, SQLite} {
+		migrations, err := loadMigrations(migrationFiles, dialect)
+		require.NoError(t, err)
+
+		var dialectVersions []int
+		for _, migration := range migrations {
+			assert.NotEmpty(t, migration.Checksum)
+			dialectVersions = append(dialectVersions, migration.Version)
+		}
+		versions = append(versions, dialectVersions)
+	}
+	assert.Equal(t, versions[0], versions[1])
+	assert.Equal(t, versions[0], versions[2])
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"unnumbered file", fstest.MapFS{"migrations/sqlite/accounts.up.sql": {}}},
+		{"unknown direction", fstest.MapFS{"migrations/sqlite/0001_accounts.sideways.sql": {}}},
+		{"missing down file", fstest.MapFS{"migrations/sqlite/0001_accounts.up.sql": {Data: []byte("SELECT 1")}}},
+		{"two names", fstest.MapFS{
+			"migrations/sqlite/0001_accounts.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/sqlite/0001_balances.down.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadMigrations(tt.files, SQLite)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := `
+		-- a comment
+		CREATE TABLE a (id INTEGER);
+
+		CREATE TABLE b (id INTEGER);
+	`
+	assert.Equal(t, []string{"-- a comment\n\t\tCREATE TABLE a (id INTEGER)", "CREATE TABLE b (id INTEGER)"}, statements(script, SQLite))
+}
+
+func TestStatements_IgnoresQuotedSemicolons(t *testing.T) {
+	script := `
+		INSERT INTO a VALUES ('x;y', "z;", 'it''s;');
+		-- a comment; with a semicolon
+		/* another; */ INSERT INTO a VALUES ('w');
+		-- a trailing comment;
+	`
+	assert.Equal(t, []string{
+		`INSERT INTO a VALUES ('x;y', "z;", 'it''s;')`,
+		"-- a comment; with a semicolon\n\t\t/* another; */ INSERT INTO a VALUES ('w')",
+	}, statements(script, SQLite))
+
+	mysql := `INSERT INTO a VALUES ('\';');
+		# a MySQL comment;
+		SELECT 1;`
+	assert.Equal(t, []string{`INSERT INTO a VALUES ('\';')`, "# a MySQL comment;\n\t\tSELECT 1"}, statements(mysql, MySQL))
+}
+
+func TestStatements_KeepsRoutineBodies(t *testing.T) {
+	trigger := `CREATE TRIGGER audit AFTER UPDATE ON a
+		BEGIN
+			INSERT INTO log VALUES (CASE WHEN NEW.id > 0 THEN 'up;' ELSE 'down' END);
+			UPDATE b SET n = n + 1;
+		END`
+	assert.Equal(t, []string{trigger, "SELECT 1"}, statements(trigger+";\nSELECT 1;", SQLite))
+
+	procedure := `CREATE PROCEDURE touch()
+		BEGIN
+			IF 1 THEN
+				UPDATE a SET id = id;
+			END IF;
+			CASE WHEN 1 THEN SELECT 1; ELSE SELECT 2; END CASE;
+		END`
+	assert.Equal(t, []string{procedure, "SELECT 1"}, statements(procedure+";\nSELECT 1;", MySQL))
+
+	function := `CREATE FUNCTION touch() RETURNS trigger AS $body$
+		BEGIN
+			UPDATE a SET id = id;
+			RETURN NEW;
+		END;
+		$body$ LANGUAGE plpgsql`
+	assert.Equal(t, []string{function, "SELECT $1"}, statements(function+";\nSELECT $1;", Postgres))
+}
@@ -0,0 +1,3 @@
+DROP TABLE account_status_changes;
+DROP TABLE ledger_entries;
+DROP TABLE accounts;
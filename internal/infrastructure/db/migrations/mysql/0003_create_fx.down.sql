@@ -0,0 +1,2 @@
+DROP TABLE fx_conversions;
+DROP TABLE fx_rates;
@@ -0,0 +1,21 @@
+CREATE TABLE fx_rates (
+	base CHAR(3) NOT NULL,
+	quote CHAR(3) NOT NULL,
+	rate DECIMAL(24, 12) NOT NULL,
+	spread_bps INT NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (base, quote)
+);
+
+CREATE TABLE fx_conversions (
+	id VARCHAR(64) PRIMARY KEY,
+	source_amount BIGINT NOT NULL,
+	source_currency CHAR(3) NOT NULL,
+	converted_amount BIGINT NOT NULL,
+	converted_currency CHAR(3) NOT NULL,
+	rate DECIMAL(24, 12) NOT NULL,
+	spread_bps INT NOT NULL,
+	rate_as_of DATETIME(6) NOT NULL,
+	spread DECIMAL(30, 12) NOT NULL,
+	rounding DECIMAL(30, 12) NOT NULL
+);
@@ -0,0 +1,11 @@
+-- Domain events waiting to be published, in the order they were written
+CREATE TABLE outbox (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	event_id VARCHAR(64) NOT NULL UNIQUE,
+	event_type VARCHAR(64) NOT NULL,
+	ordering_key VARCHAR(255) NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at DATETIME(6) NOT NULL,
+	published_at DATETIME(6) NULL,
+	INDEX idx_outbox_unpublished (published_at, id)
+);
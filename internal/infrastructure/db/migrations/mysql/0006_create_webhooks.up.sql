@@ -0,0 +1,25 @@
+-- Partner endpoints events are pushed to, subscribed to a comma-separated list of event types
+CREATE TABLE webhooks (
+	id VARCHAR(64) PRIMARY KEY,
+	url TEXT NOT NULL,
+	event_types TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	created_at DATETIME(6) NOT NULL
+);
+
+-- The delivery log: every event on its way to a webhook, delivered or dead
+CREATE TABLE webhook_deliveries (
+	id VARCHAR(64) PRIMARY KEY,
+	webhook_id VARCHAR(64) NOT NULL,
+	event_id VARCHAR(64) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt_at DATETIME(6) NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at DATETIME(6) NOT NULL,
+	updated_at DATETIME(6) NOT NULL,
+	INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+	INDEX idx_webhook_deliveries_webhook (webhook_id, created_at)
+);
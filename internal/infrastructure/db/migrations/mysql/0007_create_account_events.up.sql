@@ -0,0 +1,30 @@
+-- Event-sourced accounts: the head of the stream of each account, holding
+-- its version and the sequence of its last event
+CREATE TABLE account_streams (
+	account_id VARCHAR(255) PRIMARY KEY,
+	version INT NOT NULL,
+	sequence INT NOT NULL
+);
+
+CREATE TABLE account_events (
+	account_id VARCHAR(255) NOT NULL,
+	sequence INT NOT NULL,
+	event_id VARCHAR(64) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (account_id, sequence)
+);
+
+-- The state of an account once the event at sequence was applied, so
+-- rebuilding it only replays the events after
+CREATE TABLE account_snapshots (
+	account_id VARCHAR(255) NOT NULL,
+	sequence INT NOT NULL,
+	owner VARCHAR(255) NOT NULL,
+	balance BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	occurred_at DATETIME(6) NOT NULL,
+	PRIMARY KEY (account_id, sequence)
+);
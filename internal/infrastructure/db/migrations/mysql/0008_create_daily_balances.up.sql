@@ -0,0 +1,8 @@
+-- The balance each account ended a day with, day being the midnight UTC
+-- starting it, kept as the account is saved during the day
+CREATE TABLE daily_balances (
+	account_id VARCHAR(255) NOT NULL,
+	day DATETIME(6) NOT NULL,
+	balance BIGINT NOT NULL,
+	PRIMARY KEY (account_id, day)
+);
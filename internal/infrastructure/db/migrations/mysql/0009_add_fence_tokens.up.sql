@@ -0,0 +1,4 @@
+-- The fencing token of the latest distributed lock each account was saved
+-- under, so the holder of an older lock cannot overwrite it
+ALTER TABLE accounts ADD COLUMN fence_token BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE account_streams ADD COLUMN fence_token BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE account_streams DROP COLUMN opened_at;
+ALTER TABLE accounts DROP COLUMN opened_at;
@@ -0,0 +1,35 @@
+-- Databases that predate migrations already have an accounts table with
+-- just an id and a balance, which is adopted rather than created. Their
+-- accounts hold euros, the one currency the service handled then.
+CREATE TABLE IF NOT EXISTS accounts (
+	id VARCHAR(255) PRIMARY KEY,
+	balance BIGINT NOT NULL
+);
+
+ALTER TABLE accounts ADD COLUMN owner VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE accounts ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE accounts ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+CREATE TABLE ledger_entries (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	journal_id VARCHAR(64) NOT NULL,
+	account_id VARCHAR(255) NOT NULL,
+	amount BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries (account_id);
+
+CREATE TABLE account_status_changes (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	account_id VARCHAR(255) NOT NULL,
+	from_status VARCHAR(16) NOT NULL,
+	to_status VARCHAR(16) NOT NULL,
+	reason TEXT NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_account_status_changes_account ON account_status_changes (account_id);
@@ -0,0 +1,19 @@
+CREATE TABLE transfers (
+	id VARCHAR(64) PRIMARY KEY,
+	from_account_id VARCHAR(255) NOT NULL,
+	to_account_id VARCHAR(255) NOT NULL,
+	amount BIGINT NOT NULL,
+	currency CHAR(3) NOT NULL,
+	conversion_id VARCHAR(64) NULL,
+	status VARCHAR(16) NOT NULL,
+	failure_reason TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE idempotency_keys (
+	idempotency_key VARCHAR(255) PRIMARY KEY,
+	request_hash CHAR(64) NOT NULL,
+	transfer_id VARCHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
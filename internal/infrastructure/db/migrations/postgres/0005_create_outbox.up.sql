@@ -0,0 +1,12 @@
+-- Domain events waiting to be published, in the order they were written
+CREATE TABLE outbox (
+	id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	event_id VARCHAR(64) NOT NULL UNIQUE,
+	event_type VARCHAR(64) NOT NULL,
+	ordering_key VARCHAR(255) NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at TIMESTAMPTZ NOT NULL,
+	published_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,26 @@
+-- Partner endpoints events are pushed to, subscribed to a comma-separated list of event types
+CREATE TABLE webhooks (
+	id VARCHAR(64) PRIMARY KEY,
+	url TEXT NOT NULL,
+	event_types TEXT NOT NULL,
+	secret VARCHAR(255) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+-- The delivery log: every event on its way to a webhook, delivered or dead
+CREATE TABLE webhook_deliveries (
+	id VARCHAR(64) PRIMARY KEY,
+	webhook_id VARCHAR(64) NOT NULL,
+	event_id VARCHAR(64) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	attempts INT NOT NULL,
+	next_attempt_at TIMESTAMPTZ NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,3 @@
+DROP TABLE account_snapshots;
+DROP TABLE account_events;
+DROP TABLE account_streams;
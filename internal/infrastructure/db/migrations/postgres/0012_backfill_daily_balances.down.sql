@@ -0,0 +1,3 @@
+-- Daily balances only save reading the ledger, so dropping the ones kept
+-- since loses nothing
+DELETE FROM daily_balances;
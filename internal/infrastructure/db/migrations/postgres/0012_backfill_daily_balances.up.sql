@@ -0,0 +1,18 @@
+-- The balances accounts ended each day of their ledger with, for the days
+-- before daily balances were kept: the current balance of the account less
+-- the entries written after the day. Accounts stored as their events are
+-- rebuilt as they were instead, so they need none. Days already kept are
+-- left as they are.
+INSERT INTO daily_balances (account_id, day, balance)
+SELECT days.account_id, days.day, accounts.balance - COALESCE((
+	SELECT SUM(later.amount) FROM ledger_entries later
+	WHERE later.account_id = days.account_id AND later.created_at >= days.day_end
+), 0)
+FROM (
+	SELECT DISTINCT account_id, date_trunc('day', created_at, 'UTC') AS day, date_trunc('day', created_at, 'UTC') + INTERVAL '1 day' AS day_end
+	FROM ledger_entries
+) days
+JOIN accounts ON accounts.id = days.account_id
+WHERE NOT EXISTS (
+	SELECT 1 FROM daily_balances kept WHERE kept.account_id = days.account_id AND kept.day = days.day
+);
@@ -0,0 +1,35 @@
+-- Timestamps are stored as UTC text and decimals as text, so neither loses precision
+-- Databases that predate migrations already have an accounts table with
+-- just an id and a balance, which is adopted rather than created. Their
+-- accounts hold euros, the one currency the service handled then.
+CREATE TABLE IF NOT EXISTS accounts (
+	id TEXT PRIMARY KEY,
+	balance INTEGER NOT NULL
+);
+
+ALTER TABLE accounts ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE accounts ADD COLUMN currency TEXT NOT NULL DEFAULT 'EUR';
+ALTER TABLE accounts ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+
+CREATE TABLE ledger_entries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	journal_id TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_ledger_entries_account ON ledger_entries (account_id);
+
+CREATE TABLE account_status_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	account_id TEXT NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_account_status_changes_account ON account_status_changes (account_id);
@@ -0,0 +1,19 @@
+CREATE TABLE transfers (
+	id TEXT PRIMARY KEY,
+	from_account_id TEXT NOT NULL,
+	to_account_id TEXT NOT NULL,
+	amount INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	conversion_id TEXT NULL,
+	status TEXT NOT NULL,
+	failure_reason TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE idempotency_keys (
+	idempotency_key TEXT PRIMARY KEY,
+	request_hash TEXT NOT NULL,
+	transfer_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
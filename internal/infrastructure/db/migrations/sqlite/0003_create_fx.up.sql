@@ -0,0 +1,21 @@
+CREATE TABLE fx_rates (
+	base TEXT NOT NULL,
+	quote TEXT NOT NULL,
+	rate TEXT NOT NULL,
+	spread_bps INTEGER NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (base, quote)
+);
+
+CREATE TABLE fx_conversions (
+	id TEXT PRIMARY KEY,
+	source_amount INTEGER NOT NULL,
+	source_currency TEXT NOT NULL,
+	converted_amount INTEGER NOT NULL,
+	converted_currency TEXT NOT NULL,
+	rate TEXT NOT NULL,
+	spread_bps INTEGER NOT NULL,
+	rate_as_of DATETIME NOT NULL,
+	spread TEXT NOT NULL,
+	rounding TEXT NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Accounts saved before versioning count as saved once
+ALTER TABLE accounts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+-- Domain events waiting to be published, in the order they were written
+CREATE TABLE outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT NOT NULL UNIQUE,
+	event_type TEXT NOT NULL,
+	ordering_key TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at DATETIME NOT NULL,
+	published_at DATETIME NULL
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
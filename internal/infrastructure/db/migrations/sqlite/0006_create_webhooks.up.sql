@@ -0,0 +1,26 @@
+-- Partner endpoints events are pushed to, subscribed to a comma-separated list of event types
+CREATE TABLE webhooks (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	event_types TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+-- The delivery log: every event on its way to a webhook, delivered or dead
+CREATE TABLE webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL,
+	event_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at);
@@ -0,0 +1,30 @@
+-- Event-sourced accounts: the head of the stream of each account, holding
+-- its version and the sequence of its last event
+CREATE TABLE account_streams (
+	account_id TEXT PRIMARY KEY,
+	version INTEGER NOT NULL,
+	sequence INTEGER NOT NULL
+);
+
+CREATE TABLE account_events (
+	account_id TEXT NOT NULL,
+	sequence INTEGER NOT NULL,
+	event_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	occurred_at DATETIME NOT NULL,
+	PRIMARY KEY (account_id, sequence)
+);
+
+-- The state of an account once the event at sequence was applied, so
+-- rebuilding it only replays the events after
+CREATE TABLE account_snapshots (
+	account_id TEXT NOT NULL,
+	sequence INTEGER NOT NULL,
+	owner TEXT NOT NULL,
+	balance INTEGER NOT NULL,
+	currency TEXT NOT NULL,
+	status TEXT NOT NULL,
+	occurred_at DATETIME NOT NULL,
+	PRIMARY KEY (account_id, sequence)
+);
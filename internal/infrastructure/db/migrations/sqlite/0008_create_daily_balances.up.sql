@@ -0,0 +1,8 @@
+-- The balance each account ended a day with, day being the midnight UTC
+-- starting it, kept as the account is saved during the day
+CREATE TABLE daily_balances (
+	account_id TEXT NOT NULL,
+	day DATETIME NOT NULL,
+	balance INTEGER NOT NULL,
+	PRIMARY KEY (account_id, day)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE account_streams DROP COLUMN fence_token;
+ALTER TABLE accounts DROP COLUMN fence_token;
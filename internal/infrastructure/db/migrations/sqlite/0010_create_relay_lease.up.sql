@@ -0,0 +1,9 @@
+-- The lease of the relay publishing the outbox, held by one relay at a time
+-- so the events are published in order. It starts out expired.
+CREATE TABLE relay_lease (
+	id INTEGER PRIMARY KEY,
+	holder TEXT NOT NULL,
+	expires_at DATETIME NOT NULL
+);
+
+INSERT INTO relay_lease (id, holder, expires_at) VALUES (1, '', '2000-01-01 00:00:00');
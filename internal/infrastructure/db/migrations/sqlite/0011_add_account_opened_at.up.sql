@@ -0,0 +1,4 @@
+-- When each account was first saved, so no balance is made up for before
+-- then. It is unknown for the accounts saved before it was recorded.
+ALTER TABLE accounts ADD COLUMN opened_at DATETIME NULL;
+ALTER TABLE account_streams ADD COLUMN opened_at DATETIME NULL;
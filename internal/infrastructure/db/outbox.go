@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+const saveOutboxMessageQuery = `INSERT INTO outbox (event_id, event_type, ordering_key, payload, occurred_at)
+								VALUES (?, ?, ?, ?, ?)`
+const findPendingOutboxMessagesQuery = `SELECT id, event_id, event_type, ordering_key, payload, occurred_at FROM outbox
+								WHERE published_at IS NULL ORDER BY id LIMIT ?`
+const markOutboxMessagePublishedQuery = `UPDATE outbox SET published_at = ? WHERE id = ?`
+const acquireRelayLeaseQuery = `UPDATE relay_lease SET holder = ?, expires_at = ?
+								WHERE id = 1 AND (holder = ? OR expires_at < ?)`
+
+// OutboxRepository implements usecases.Outbox on the outbox table. Published
+// messages are kept, with the time they were published. The relay lease is
+// the single row of the relay_lease table.
+type OutboxRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	unit    *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *OutboxRepository) inTx(u *unit) *OutboxRepository {
+	return &OutboxRepository{db: r.db, dialect: r.dialect, unit: u}
+}
+
+func (r *OutboxRepository) AcquireRelayLease(ctx context.Context, holder string, now, expiresAt time.Time) (bool, error) {
+	q := r.unit.querier(r.db, r.dialect)
+	result, err := q.ExecContext(ctx, acquireRelayLeaseQuery, holder, expiresAt, holder, now)
+	if err != nil {
+		return false, translate(err)
+	}
+	acquired, err := result.RowsAffected()
+	return acquired == 1, err
+}
+
+func (r *OutboxRepository) PendingMessages(ctx context.Context, limit int) ([]usecases.OutboxMessage, error) {
+	rows, err := r.unit.querier(r.db, r.dialect).QueryContext(ctx, findPendingOutboxMessagesQuery, limit)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var messages []usecases.OutboxMessage
+	for rows.Next() {
+		var message usecases.OutboxMessage
+		err := rows.Scan(&message.ID, &message.EventID, &message.EventType, &message.OrderingKey, &message.Payload, &message.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, translate(rows.Err())
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, ids ...int64) error {
+	now := time.Now()
+	for _, id := range ids {
+		if _, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, markOutboxMessagePublishedQuery, now, id); err != nil {
+			return translate(err)
+		}
+	}
+	return nil
+}
+
+// saveEvents adds events to the outbox of the transaction of q
+func saveEvents(ctx context.Context, q querier, events []banking.Event) error {
+	for _, event := range events {
+		message, err := usecases.NewOutboxMessage(event)
+		if err != nil {
+			return err
+		}
+
+		args := []any{message.EventID, message.EventType, message.OrderingKey, string(message.Payload), message.OccurredAt}
+		if _, err := q.ExecContext(ctx, saveOutboxMessageQuery, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewOutboxRepository(db *sql.DB, dialect Dialect) *OutboxRepository {
+	return &OutboxRepository{db: db, dialect: dialect}
+}
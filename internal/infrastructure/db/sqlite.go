@@ -0,0 +1,24 @@
+package db
+
+import (
+	"database/sql"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteBusyTimeout is how long, in milliseconds, a transaction waits for
+// another one to release the database before failing with a conflict
+const sqliteBusyTimeout = "5000"
+
+// OpenSQLite opens the SQLite database at path, creating it if needed. Its
+// transactions begin with BEGIN IMMEDIATE, taking the write lock up front,
+// so they run one at a time as if every row they read was locked.
+func OpenSQLite(path string) (*sql.DB, error) {
+	params := url.Values{}
+	params.Set("_txlock", "immediate")
+	params.Add("_pragma", "busy_timeout("+sqliteBusyTimeout+")")
+	params.Add("_pragma", "journal_mode(WAL)")
+
+	return sql.Open("sqlite", "file:"+path+"?"+params.Encode())
+}
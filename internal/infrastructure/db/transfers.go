@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+const findTransferQuery = `SELECT id, from_account_id, to_account_id, amount, currency, conversion_id, status, 
+								failure_reason, created_at, updated_at FROM transfers WHERE id = ?`
+const insertTransferQuery = `INSERT INTO transfers (id, from_account_id, to_account_id, amount, currency, conversion_id, 
+								status, failure_reason, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+type TransferRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	unit    *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *TransferRepository) inTx(u *unit) *TransferRepository {
+	return &TransferRepository{db: r.db, dialect: r.dialect, unit: u}
+}
+
+func (r *TransferRepository) FindTransfer(ctx context.Context, id string) (*banking.MoneyTransfer, error) {
+	row := r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, findTransferQuery, id)
+
+	var transfer banking.MoneyTransfer
+	var conversionID sql.NullString
+	err := row.Scan(
+		&transfer.ID,
+		&transfer.From,
+		&transfer.To,
+		&transfer.Amount.Amount,
+		&transfer.Amount.Currency,
+		&conversionID,
+		&transfer.Status,
+		&transfer.FailureReason,
+		&transfer.CreatedAt,
+		&transfer.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, banking.ErrTransferNotFound
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+	transfer.ConversionID = conversionID.String
+	return &transfer, nil
+}
+
+// SaveTransfer writes the transfer with its pending events. Outside a
+// transaction, it runs in one of its own.
+func (r *TransferRepository) SaveTransfer(ctx context.Context, transfer *banking.MoneyTransfer) error {
+	if r.unit == nil {
+		return withinTx(ctx, r.db, Pessimistic, func(u *unit) error {
+			return r.inTx(u).SaveTransfer(ctx, transfer)
+		})
+	}
+
+	conversionID := sql.NullString{String: transfer.ConversionID, Valid: transfer.ConversionID != ""}
+	args := []any{
+		transfer.ID,
+		transfer.From,
+		transfer.To,
+		transfer.Amount.Amount,
+		transfer.Amount.Currency,
+		conversionID,
+		transfer.Status,
+		transfer.FailureReason,
+		transfer.CreatedAt,
+		transfer.UpdatedAt,
+	}
+
+	query := r.dialect.upsert(insertTransferQuery, "id", "status", "failure_reason", "updated_at")
+	if _, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, query, args...); err != nil {
+		return translate(err)
+	}
+
+	if err := saveEvents(ctx, r.unit.querier(r.db, r.dialect), transfer.PendingEvents()); err != nil {
+		return translate(err)
+	}
+	transfer.ClearPendingEvents()
+	return nil
+}
+
+func NewTransferRepository(db *sql.DB, dialect Dialect) *TransferRepository {
+	return &TransferRepository{db: db, dialect: dialect}
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// querier is what the repositories need from either the database or a transaction
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Locking is how transactions keep others from overwriting what they read
+type Locking string
+
+const (
+	// Pessimistic transactions lock the accounts they read until they end,
+	// and run serializable
+	Pessimistic Locking = "pessimistic"
+	// Optimistic transactions read without locks at read committed, and
+	// only save accounts still at the version they read
+	Optimistic Locking = "optimistic"
+)
+
+// isolation returns the isolation level transactions run at
+func (l Locking) isolation() sql.IsolationLevel {
+	if l == Optimistic {
+		return sql.LevelReadCommitted
+	}
+	return sql.LevelSerializable
+}
+
+// unit is a running transaction. Repositories bound to one run their queries
+// in it; the others, holding a nil unit, run them straight on the database.
+type unit struct {
+	tx      *sql.Tx
+	locking Locking
+	hooks   txHooks
+}
+
+func (u *unit) querier(db *sql.DB, dialect Dialect) querier {
+	if u == nil {
+		return rebinder{querier: db, dialect: dialect}
+	}
+	return rebinder{querier: u.tx, dialect: dialect}
+}
+
+// withinTx runs fn in a transaction locking as given, and then the hooks for
+// how it ended. The database rolls the transaction back if ctx is canceled
+// before it commits.
+func withinTx(ctx context.Context, db *sql.DB, locking Locking, fn func(u *unit) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: locking.isolation()})
+	if err != nil {
+		return interrupted(ctx, translate(err))
+	}
+
+	u := &unit{tx: tx, locking: locking}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			u.hooks.ended(false)
+			panic(p)
+		}
+	}()
+
+	if err := fn(u); err != nil {
+		tx.Rollback()
+		u.hooks.ended(false)
+		return interrupted(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		u.hooks.ended(false)
+		return interrupted(ctx, translate(err))
+	}
+	u.hooks.ended(true)
+	return nil
+}
+
+// interrupted reports err as caused by ctx once ctx is done. Drivers fail in
+// their own ways when a query is canceled, and every query after it fails
+// with sql.ErrTxDone, as the transaction was rolled back.
+func interrupted(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	return err
+}
+
+// AccountStore is how accounts are stored: as their current state by
+// AccountRepository, or as their events by EventSourcedAccountRepository
+type AccountStore interface {
+	usecases.AccountRepository
+	usecases.AccountLister
+	usecases.BalanceHistory
+	FindLedgerEntries(ctx context.Context, accountID string) ([]banking.LedgerEntry, error)
+	// inTx returns the store bound to the transaction of u
+	inTx(u *unit) AccountStore
+}
+
+// UnitOfWork implements usecases.UnitOfWork with SQL transactions
+type UnitOfWork struct {
+	db          *sql.DB
+	locking     Locking
+	accounts    AccountStore
+	transfers   *TransferRepository
+	fx          *FXRepository
+	idempotency *IdempotencyRepository
+	outbox      *OutboxRepository
+	webhooks    *WebhookRepository
+}
+
+func (w *UnitOfWork) WithinTx(ctx context.Context, fn func(repos usecases.Repositories) error) error {
+	return withinTx(ctx, w.db, w.locking, func(u *unit) error {
+		return fn(&repositories{
+			unit:        u,
+			accounts:    w.accounts.inTx(u),
+			transfers:   w.transfers.inTx(u),
+			fx:          w.fx.inTx(u),
+			idempotency: w.idempotency.inTx(u),
+			outbox:      w.outbox.inTx(u),
+			webhooks:    w.webhooks.inTx(u),
+		})
+	})
+}
+
+// repositories are the repositories bound to one transaction
+type repositories struct {
+	unit        *unit
+	accounts    AccountStore
+	transfers   *TransferRepository
+	fx          *FXRepository
+	idempotency *IdempotencyRepository
+	outbox      *OutboxRepository
+	webhooks    *WebhookRepository
+}
+
+func (r *repositories) Accounts() usecases.AccountRepository {
+	return r.accounts
+}
+
+func (r *repositories) History() usecases.BalanceHistory {
+	return r.accounts
+}
+
+func (r *repositories) Transfers() usecases.TransferRepository {
+	return r.transfers
+}
+
+func (r *repositories) Conversions() usecases.ConversionRepository {
+	return r.fx
+}
+
+func (r *repositories) IdempotencyKeys() usecases.IdempotencyRepository {
+	return r.idempotency
+}
+
+func (r *repositories) Outbox() usecases.Outbox {
+	return r.outbox
+}
+
+func (r *repositories) Webhooks() usecases.WebhookRepository {
+	return r.webhooks
+}
+
+func (r *repositories) WebhookDeliveries() usecases.WebhookDeliveryRepository {
+	return r.webhooks
+}
+
+func (r *repositories) AfterCommit(hook func()) {
+	r.unit.hooks.afterCommit(hook)
+}
+
+func NewUnitOfWork(
+	db *sql.DB,
+	locking Locking,
+	accounts AccountStore,
+	transfers *TransferRepository,
+	fx *FXRepository,
+	idempotency *IdempotencyRepository,
+	outbox *OutboxRepository,
+	webhooks *WebhookRepository,
+) *UnitOfWork {
+	return &UnitOfWork{
+		db:          db,
+		locking:     locking,
+		accounts:    accounts,
+		transfers:   transfers,
+		fx:          fx,
+		idempotency: idempotency,
+		outbox:      outbox,
+		webhooks:    webhooks,
+	}
+}
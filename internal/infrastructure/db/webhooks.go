@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+const findWebhookQuery = `SELECT id, url, event_types, secret, created_at FROM webhooks WHERE id = ?`
+const listWebhooksQuery = `SELECT id, url, event_types, secret, created_at FROM webhooks ORDER BY created_at, id`
+const insertWebhookQuery = `INSERT INTO webhooks (id, url, event_types, secret, created_at) VALUES (?, ?, ?, ?, ?)`
+const deleteWebhookQuery = `DELETE FROM webhooks WHERE id = ?`
+const deleteWebhookDeliveriesQuery = `DELETE FROM webhook_deliveries WHERE webhook_id = ?`
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+								last_error, created_at, updated_at`
+const findDeliveryQuery = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = ?`
+const listDeliveriesQuery = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+								WHERE webhook_id = ? AND (? = '' OR status = ?) ORDER BY created_at DESC, id LIMIT ?`
+const findDueDeliveriesQuery = `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+								WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ? FOR UPDATE`
+const insertDeliveryQuery = `INSERT INTO webhook_deliveries (` + deliveryColumns + `)
+								VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// WebhookRepository implements usecases.WebhookRepository and
+// usecases.WebhookDeliveryRepository on the webhooks and webhook_deliveries tables
+type WebhookRepository struct {
+	db      *sql.DB
+	dialect Dialect
+	unit    *unit
+}
+
+// inTx returns the repository bound to the transaction of u
+func (r *WebhookRepository) inTx(u *unit) *WebhookRepository {
+	return &WebhookRepository{db: r.db, dialect: r.dialect, unit: u}
+}
+
+func (r *WebhookRepository) FindWebhook(ctx context.Context, id string) (*usecases.Webhook, error) {
+	webhook, err := scanWebhook(r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, findWebhookQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) ([]*usecases.Webhook, error) {
+	rows, err := r.unit.querier(r.db, r.dialect).QueryContext(ctx, listWebhooksQuery)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var webhooks []*usecases.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, translate(rows.Err())
+}
+
+func (r *WebhookRepository) SaveWebhook(ctx context.Context, webhook *usecases.Webhook) error {
+	args := []any{webhook.ID, webhook.URL, strings.Join(webhook.EventTypes, ","), webhook.Secret, webhook.CreatedAt}
+	query := r.dialect.upsert(insertWebhookQuery, "id", "url", "event_types", "secret")
+	_, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, query, args...)
+	return translate(err)
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	q := r.unit.querier(r.db, r.dialect)
+	result, err := q.ExecContext(ctx, deleteWebhookQuery, id)
+	if err != nil {
+		return translate(err)
+	}
+	if deleted, err := result.RowsAffected(); err != nil {
+		return err
+	} else if deleted == 0 {
+		return usecases.ErrWebhookNotFound
+	}
+
+	_, err = q.ExecContext(ctx, deleteWebhookDeliveriesQuery, id)
+	return translate(err)
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*usecases.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.unit.querier(r.db, r.dialect).QueryRowContext(ctx, findDeliveryQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, usecases.ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, translate(err)
+	}
+	return delivery, nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, status usecases.DeliveryStatus, limit int) ([]*usecases.WebhookDelivery, error) {
+	return r.queryDeliveries(ctx, listDeliveriesQuery, webhookID, status, status, limit)
+}
+
+func (r *WebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*usecases.WebhookDelivery, error) {
+	return r.queryDeliveries(ctx, findDueDeliveriesQuery, usecases.DeliveryPending, now, limit)
+}
+
+func (r *WebhookRepository) SaveDelivery(ctx context.Context, delivery *usecases.WebhookDelivery) error {
+	args := []any{
+		delivery.ID,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.LastError,
+		delivery.CreatedAt,
+		delivery.UpdatedAt,
+	}
+	query := r.dialect.upsert(insertDeliveryQuery, "id", "status", "attempts", "next_attempt_at", "last_error", "updated_at")
+	_, err := r.unit.querier(r.db, r.dialect).ExecContext(ctx, query, args...)
+	return translate(err)
+}
+
+func (r *WebhookRepository) queryDeliveries(ctx context.Context, query string, args ...any) ([]*usecases.WebhookDelivery, error) {
+	rows, err := r.unit.querier(r.db, r.dialect).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	defer rows.Close()
+
+	var deliveries []*usecases.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, translate(rows.Err())
+}
+
+// scanner is what scanning a row needs from either sql.Row or sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*usecases.Webhook, error) {
+	var webhook usecases.Webhook
+	var eventTypes string
+	if err := row.Scan(&webhook.ID, &webhook.URL, &eventTypes, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	webhook.EventTypes = strings.Split(eventTypes, ",")
+	return &webhook, nil
+}
+
+func scanDelivery(row scanner) (*usecases.WebhookDelivery, error) {
+	var delivery usecases.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.Payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastError,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func NewWebhookRepository(db *sql.DB, dialect Dialect) *WebhookRepository {
+	return &WebhookRepository{db: db, dialect: dialect}
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// AccountRepository reads the accounts committed to a Store. Save runs in a
+// transaction of its own.
+type AccountRepository struct {
+	store *Store
+}
+
+func (r *AccountRepository) Find(ctx context.Context, id string) (*banking.Account, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	account, ok := r.store.accounts[id]
+	if !ok {
+		return nil, banking.ErrAccountNotFound
+	}
+	return clone(account), nil
+}
+
+func (r *AccountRepository) Save(ctx context.Context, account *banking.Account) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.Accounts().Save(ctx, account)
+	})
+}
+
+func (r *AccountRepository) SaveFenced(ctx context.Context, account *banking.Account, token int64) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.Accounts().SaveFenced(ctx, account, token)
+	})
+}
+
+// List returns up to limit accounts ordered by ID, starting after the given one
+func (r *AccountRepository) List(ctx context.Context, after string, limit int) ([]*banking.Account, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var ids []string
+	for id := range r.store.accounts {
+		if id > after {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	accounts := make([]*banking.Account, 0, min(limit, len(ids)))
+	for _, id := range ids[:min(limit, len(ids))] {
+		accounts = append(accounts, clone(r.store.accounts[id]))
+	}
+	return accounts, nil
+}
+
+// FindLedgerEntries returns the ledger history of an account in the order it was written
+func (r *AccountRepository) FindLedgerEntries(ctx context.Context, accountID string) ([]banking.LedgerEntry, error) {
+	return r.findLedgerEntries(accountID, time.Time{}, time.Time{}), nil
+}
+
+// FindLedgerEntriesSince returns the ledger entries of an account written at or after since
+func (r *AccountRepository) FindLedgerEntriesSince(ctx context.Context, accountID string, since time.Time) ([]banking.LedgerEntry, error) {
+	return r.findLedgerEntries(accountID, since, time.Time{}), nil
+}
+
+// FindLedgerEntriesBetween returns the ledger entries of an account written within [from, to)
+func (r *AccountRepository) FindLedgerEntriesBetween(ctx context.Context, accountID string, from, to time.Time) ([]banking.LedgerEntry, error) {
+	return r.findLedgerEntries(accountID, from, to), nil
+}
+
+// findLedgerEntries returns the entries of an account written at or after
+// since, and before until unless it is zero
+func (r *AccountRepository) findLedgerEntries(accountID string, since, until time.Time) []banking.LedgerEntry {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var entries []banking.LedgerEntry
+	for _, entry := range r.store.entries {
+		if entry.AccountID == accountID && inRange(entry.CreatedAt, since, until) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// inRange tells whether t is at or after since, and before until unless it is zero
+func inRange(t, since, until time.Time) bool {
+	return !t.Before(since) && (until.IsZero() || t.Before(until))
+}
+
+// FindDailyBalance returns the balance of the account at the end of the first day on or after day it was saved
+func (r *AccountRepository) FindDailyBalance(ctx context.Context, accountID string, day time.Time) (banking.DailyBalance, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var first *banking.DailyBalance
+	for _, daily := range r.store.dailyBalances[accountID] {
+		if !daily.Day.Before(day) && (first == nil || daily.Day.Before(first.Day)) {
+			first = &daily
+		}
+	}
+	if first == nil {
+		return banking.DailyBalance{}, usecases.ErrNoDailyBalance
+	}
+	return *first, nil
+}
+
+// FindOpenedAt returns when the account was first saved
+func (r *AccountRepository) FindOpenedAt(ctx context.Context, accountID string) (time.Time, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	opened, ok := r.store.openedAt[accountID]
+	if !ok {
+		return time.Time{}, banking.ErrAccountNotFound
+	}
+	return opened, nil
+}
+
+func NewAccountRepository(store *Store) *AccountRepository {
+	return &AccountRepository{store: store}
+}
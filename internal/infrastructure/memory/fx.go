@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// FXRepository quotes the rates it was given with SetRate
+type FXRepository struct {
+	mu    sync.RWMutex
+	rates map[string]banking.Rate
+}
+
+func (r *FXRepository) Rate(ctx context.Context, base, quote string) (banking.Rate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rate, ok := r.rates[base+"/"+quote]
+	if !ok {
+		return banking.Rate{}, fmt.Errorf("fx rate %s/%s: %w", base, quote, usecases.ErrRateUnavailable)
+	}
+	return rate, nil
+}
+
+func (r *FXRepository) SetRate(rate banking.Rate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rates[rate.Base+"/"+rate.Quote] = rate
+}
+
+func NewFXRepository() *FXRepository {
+	return &FXRepository{rates: make(map[string]banking.Rate)}
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// Store keeps all the data of the service in memory. Transactions run one at
+// a time, and their changes only become visible when they commit.
+type Store struct {
+	// txMu is held by the running transaction
+	txMu sync.Mutex
+	// lastOutboxID numbers the outbox messages, under txMu
+	lastOutboxID int64
+
+	mu       sync.RWMutex
+	accounts map[string]*banking.Account
+	// fenceTokens are the fencing tokens the accounts were last saved under
+	fenceTokens map[string]int64
+	// openedAt are the times the accounts were first saved
+	openedAt      map[string]time.Time
+	entries       []banking.LedgerEntry
+	statusChanges []banking.StatusChange
+	// dailyBalances are the daily balances of each account, by day
+	dailyBalances   map[string]map[time.Time]banking.DailyBalance
+	transfers       map[string]banking.MoneyTransfer
+	conversions     map[string]banking.Conversion
+	idempotencyKeys map[string]usecases.IdempotencyKey
+	// outbox holds the messages not published yet
+	outbox     []usecases.OutboxMessage
+	relayLease relayLease
+	webhooks   map[string]usecases.Webhook
+	deliveries map[string]usecases.WebhookDelivery
+}
+
+// commit applies the changes staged by t
+func (s *Store) commit(t *tx) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, account := range t.accounts {
+		s.accounts[id] = account
+	}
+	maps.Copy(s.fenceTokens, t.fenceTokens)
+	maps.Copy(s.openedAt, t.openedAt)
+	s.entries = append(s.entries, t.entries...)
+	s.statusChanges = append(s.statusChanges, t.statusChanges...)
+	for _, daily := range t.dailyBalances {
+		if s.dailyBalances[daily.AccountID] == nil {
+			s.dailyBalances[daily.AccountID] = make(map[time.Time]banking.DailyBalance)
+		}
+		s.dailyBalances[daily.AccountID][daily.Day] = daily
+	}
+	for id, transfer := range t.transfers {
+		s.transfers[id] = transfer
+	}
+	for id, conversion := range t.conversions {
+		s.conversions[id] = conversion
+	}
+	for key, idempotencyKey := range t.idempotencyKeys {
+		s.idempotencyKeys[key] = idempotencyKey
+	}
+	if t.relayLease != nil {
+		s.relayLease = *t.relayLease
+	}
+	s.outbox = slices.DeleteFunc(append(s.outbox, t.outbox...), func(message usecases.OutboxMessage) bool {
+		return t.published[message.ID]
+	})
+	for id, webhook := range t.webhooks {
+		s.webhooks[id] = webhook
+	}
+	for id, delivery := range t.deliveries {
+		s.deliveries[id] = delivery
+	}
+	for id := range t.deletedWebhooks {
+		delete(s.webhooks, id)
+		maps.DeleteFunc(s.deliveries, func(_ string, delivery usecases.WebhookDelivery) bool {
+			return delivery.WebhookID == id
+		})
+	}
+}
+
+// relayLease is held by the relay publishing the outbox
+type relayLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+// clone copies an account without its lock or pending changes
+func clone(account *banking.Account) *banking.Account {
+	return &banking.Account{
+		ID:       account.ID,
+		Owner:    account.Owner,
+		Balance:  account.Balance,
+		Currency: account.Currency,
+		Status:   account.Status,
+		Version:  account.Version,
+	}
+}
+
+func NewStore() *Store {
+	return &Store{
+		accounts:        make(map[string]*banking.Account),
+		fenceTokens:     make(map[string]int64),
+		openedAt:        make(map[string]time.Time),
+		dailyBalances:   make(map[string]map[time.Time]banking.DailyBalance),
+		transfers:       make(map[string]banking.MoneyTransfer),
+		conversions:     make(map[string]banking.Conversion),
+		idempotencyKeys: make(map[string]usecases.IdempotencyKey),
+		webhooks:        make(map[string]usecases.Webhook),
+		deliveries:      make(map[string]usecases.WebhookDelivery),
+	}
+}
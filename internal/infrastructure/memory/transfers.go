@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// TransferRepository reads the transfers committed to a Store. SaveTransfer
+// runs in a transaction of its own.
+type TransferRepository struct {
+	store *Store
+}
+
+func (r *TransferRepository) FindTransfer(ctx context.Context, id string) (*banking.MoneyTransfer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	transfer, ok := r.store.transfers[id]
+	if !ok {
+		return nil, banking.ErrTransferNotFound
+	}
+	return &transfer, nil
+}
+
+func (r *TransferRepository) SaveTransfer(ctx context.Context, transfer *banking.MoneyTransfer) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.Transfers().SaveTransfer(ctx, transfer)
+	})
+}
+
+func NewTransferRepository(store *Store) *TransferRepository {
+	return &TransferRepository{store: store}
+}
@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+)
+
+// UnitOfWork implements usecases.UnitOfWork on a Store
+type UnitOfWork struct {
+	store *Store
+}
+
+func (w *UnitOfWork) WithinTx(ctx context.Context, fn func(repos usecases.Repositories) error) error {
+	w.store.txMu.Lock()
+	defer w.store.txMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t := newTx(w.store)
+	if err := fn(t); err != nil {
+		return err
+	}
+
+	// A transaction canceled before committing is rolled back
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	w.store.commit(t)
+	for _, hook := range t.hooks {
+		hook()
+	}
+	return nil
+}
+
+func NewUnitOfWork(store *Store) *UnitOfWork {
+	return &UnitOfWork{store: store}
+}
+
+// tx stages the changes of a transaction until it commits. Reads see the
+// staged changes first, then the committed data.
+type tx struct {
+	store           *Store
+	accounts        map[string]*banking.Account
+	fenceTokens     map[string]int64
+	openedAt        map[string]time.Time
+	entries         []banking.LedgerEntry
+	statusChanges   []banking.StatusChange
+	dailyBalances   []banking.DailyBalance
+	transfers       map[string]banking.MoneyTransfer
+	conversions     map[string]banking.Conversion
+	idempotencyKeys map[string]usecases.IdempotencyKey
+	outbox          []usecases.OutboxMessage
+	published       map[int64]bool
+	relayLease      *relayLease
+	webhooks        map[string]usecases.Webhook
+	deletedWebhooks map[string]bool
+	deliveries      map[string]usecases.WebhookDelivery
+	hooks           []func()
+}
+
+func newTx(store *Store) *tx {
+	return &tx{
+		store:           store,
+		accounts:        make(map[string]*banking.Account),
+		fenceTokens:     make(map[string]int64),
+		openedAt:        make(map[string]time.Time),
+		transfers:       make(map[string]banking.MoneyTransfer),
+		conversions:     make(map[string]banking.Conversion),
+		idempotencyKeys: make(map[string]usecases.IdempotencyKey),
+		published:       make(map[int64]bool),
+		webhooks:        make(map[string]usecases.Webhook),
+		deletedWebhooks: make(map[string]bool),
+		deliveries:      make(map[string]usecases.WebhookDelivery),
+	}
+}
+
+func (t *tx) Accounts() usecases.AccountRepository {
+	return txAccounts{t}
+}
+
+func (t *tx) History() usecases.BalanceHistory {
+	return txHistory{t}
+}
+
+func (t *tx) Transfers() usecases.TransferRepository {
+	return txTransfers{t}
+}
+
+func (t *tx) Conversions() usecases.ConversionRepository {
+	return txConversions{t}
+}
+
+func (t *tx) IdempotencyKeys() usecases.IdempotencyRepository {
+	return txIdempotencyKeys{t}
+}
+
+func (t *tx) Outbox() usecases.Outbox {
+	return txOutbox{t}
+}
+
+func (t *tx) Webhooks() usecases.WebhookRepository {
+	return txWebhooks{t}
+}
+
+func (t *tx) WebhookDeliveries() usecases.WebhookDeliveryRepository {
+	return txWebhooks{t}
+}
+
+func (t *tx) AfterCommit(hook func()) {
+	t.hooks = append(t.hooks, hook)
+}
+
+type txAccounts struct{ *tx }
+
+func (t txAccounts) Find(ctx context.Context, id string) (*banking.Account, error) {
+	if account, ok := t.accounts[id]; ok {
+		return clone(account), nil
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	account, ok := t.store.accounts[id]
+	if !ok {
+		return nil, banking.ErrAccountNotFound
+	}
+	return clone(account), nil
+}
+
+// Save fails with usecases.ErrConcurrentModification unless the account has
+// the version last saved, and increments it
+func (t txAccounts) Save(ctx context.Context, account *banking.Account) error {
+	if account.Version != t.version(account.ID) {
+		return usecases.ErrConcurrentModification
+	}
+	if account.Version == 0 {
+		t.openedAt[account.ID] = time.Now().UTC()
+	}
+	account.Version++
+	t.accounts[account.ID] = clone(account)
+	t.dailyBalances = append(t.dailyBalances, account.DailyBalance())
+
+	t.entries = append(t.entries, account.PendingEntries()...)
+	account.ClearPendingEntries()
+
+	t.statusChanges = append(t.statusChanges, account.PendingStatusChanges()...)
+	account.ClearPendingStatusChanges()
+	if err := t.saveEvents(account.PendingEvents()); err != nil {
+		return err
+	}
+	account.ClearPendingEvents()
+	return nil
+}
+
+// SaveFenced fails with usecases.ErrLockLost if the account was saved under
+// a greater fencing token than token, and saves it otherwise
+func (t txAccounts) SaveFenced(ctx context.Context, account *banking.Account, token int64) error {
+	if token < t.fenceToken(account.ID) {
+		return usecases.ErrLockLost
+	}
+	if err := t.Save(ctx, account); err != nil {
+		return err
+	}
+	t.fenceTokens[account.ID] = token
+	return nil
+}
+
+// fenceToken returns the fencing token an account was last saved under
+func (t txAccounts) fenceToken(id string) int64 {
+	if token, ok := t.fenceTokens[id]; ok {
+		return token
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	return t.store.fenceTokens[id]
+}
+
+// version returns the version of an account in the transaction, or 0 if it was never saved
+func (t txAccounts) version(id string) int {
+	if account, ok := t.accounts[id]; ok {
+		return account.Version
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	if account, ok := t.store.accounts[id]; ok {
+		return account.Version
+	}
+	return 0
+}
+
+// txHistory reads the history committed by AccountRepository, along with
+// the history staged by the transaction
+type txHistory struct{ *tx }
+
+func (t txHistory) FindLedgerEntriesSince(ctx context.Context, accountID string, since time.Time) ([]banking.LedgerEntry, error) {
+	return t.findLedgerEntries(accountID, since, time.Time{}), nil
+}
+
+func (t txHistory) FindLedgerEntriesBetween(ctx context.Context, accountID string, from, to time.Time) ([]banking.LedgerEntry, error) {
+	return t.findLedgerEntries(accountID, from, to), nil
+}
+
+func (t txHistory) findLedgerEntries(accountID string, since, until time.Time) []banking.LedgerEntry {
+	entries := NewAccountRepository(t.store).findLedgerEntries(accountID, since, until)
+	for _, entry := range t.entries {
+		if entry.AccountID == accountID && inRange(entry.CreatedAt, since, until) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (t txHistory) FindOpenedAt(ctx context.Context, accountID string) (time.Time, error) {
+	if opened, ok := t.openedAt[accountID]; ok {
+		return opened, nil
+	}
+	return NewAccountRepository(t.store).FindOpenedAt(ctx, accountID)
+}
+
+// FindDailyBalance prefers the balance staged for a day to the one committed
+func (t txHistory) FindDailyBalance(ctx context.Context, accountID string, day time.Time) (banking.DailyBalance, error) {
+	first, err := NewAccountRepository(t.store).FindDailyBalance(ctx, accountID, day)
+	if err != nil && !errors.Is(err, usecases.ErrNoDailyBalance) {
+		return banking.DailyBalance{}, err
+	}
+	found := err == nil
+	for _, daily := range t.dailyBalances {
+		if daily.AccountID == accountID && !daily.Day.Before(day) && (!found || !daily.Day.After(first.Day)) {
+			first, found = daily, true
+		}
+	}
+	if !found {
+		return banking.DailyBalance{}, usecases.ErrNoDailyBalance
+	}
+	return first, nil
+}
+
+type txTransfers struct{ *tx }
+
+func (t txTransfers) FindTransfer(ctx context.Context, id string) (*banking.MoneyTransfer, error) {
+	if transfer, ok := t.transfers[id]; ok {
+		return &transfer, nil
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	transfer, ok := t.store.transfers[id]
+	if !ok {
+		return nil, banking.ErrTransferNotFound
+	}
+	return &transfer, nil
+}
+
+func (t txTransfers) SaveTransfer(ctx context.Context, transfer *banking.MoneyTransfer) error {
+	if err := t.saveEvents(transfer.PendingEvents()); err != nil {
+		return err
+	}
+	transfer.ClearPendingEvents()
+	t.transfers[transfer.ID] = *transfer
+	return nil
+}
+
+type txConversions struct{ *tx }
+
+func (t txConversions) SaveConversion(ctx context.Context, conversion banking.Conversion) error {
+	t.conversions[conversion.ID] = conversion
+	return nil
+}
+
+type txIdempotencyKeys struct{ *tx }
+
+func (t txIdempotencyKeys) FindIdempotencyKey(ctx context.Context, key string) (*usecases.IdempotencyKey, error) {
+	if idempotencyKey, ok := t.idempotencyKeys[key]; ok {
+		return &idempotencyKey, nil
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+	idempotencyKey, ok := t.store.idempotencyKeys[key]
+	if !ok {
+		return nil, nil
+	}
+	return &idempotencyKey, nil
+}
+
+func (t txIdempotencyKeys) SaveIdempotencyKey(ctx context.Context, key usecases.IdempotencyKey) error {
+	t.idempotencyKeys[key.Key] = key
+	return nil
+}
+
+type txOutbox struct{ *tx }
+
+func (t txOutbox) AcquireRelayLease(ctx context.Context, holder string, now, expiresAt time.Time) (bool, error) {
+	lease := t.relayLease
+	if lease == nil {
+		t.store.mu.RLock()
+		lease = &t.store.relayLease
+		t.store.mu.RUnlock()
+	}
+	if lease.holder != holder && !lease.expiresAt.Before(now) {
+		return false, nil
+	}
+	t.relayLease = &relayLease{holder: holder, expiresAt: expiresAt}
+	return true, nil
+}
+
+func (t txOutbox) PendingMessages(ctx context.Context, limit int) ([]usecases.OutboxMessage, error) {
+	t.store.mu.RLock()
+	pending := append(slices.Clone(t.store.outbox), t.outbox...)
+	t.store.mu.RUnlock()
+
+	pending = slices.DeleteFunc(pending, func(message usecases.OutboxMessage) bool {
+		return t.published[message.ID]
+	})
+	return pending[:min(limit, len(pending))], nil
+}
+
+func (t txOutbox) MarkPublished(ctx context.Context, ids ...int64) error {
+	for _, id := range ids {
+		t.published[id] = true
+	}
+	return nil
+}
+
+// saveEvents stages events in the outbox, numbering them in order
+func (t *tx) saveEvents(events []banking.Event) error {
+	for _, event := range events {
+		message, err := usecases.NewOutboxMessage(event)
+		if err != nil {
+			return err
+		}
+
+		t.store.lastOutboxID++
+		message.ID = t.store.lastOutboxID
+		t.outbox = append(t.outbox, message)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/ppicom/newtonian/internal/domain/banking"
+	"github.com/ppicom/newtonian/internal/infrastructure/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var errAborted = errors.New("aborted")
+
+func findAccount(t *testing.T, unitOfWork usecases.UnitOfWork, id string) *banking.Account {
+	t.Helper()
+	var account *banking.Account
+	err := unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) (err error) {
+		account, err = repos.Accounts().Find(t.Context(), id)
+		return err
+	})
+	require.NoError(t, err)
+	return account
+}
+
+func saveAccount(t *testing.T, unitOfWork usecases.UnitOfWork, account *banking.Account) {
+	t.Helper()
+	err := unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		return repos.Accounts().Save(t.Context(), account)
+	})
+	require.NoError(t, err)
+}
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	unitOfWork := memory.NewUnitOfWork(memory.NewStore())
+	saveAccount(t, unitOfWork, &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"})
+
+	var committed bool
+	err := unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(t.Context(), "acc1")
+		require.NoError(t, err)
+		require.NoError(t, banking.Withdraw(account, 30))
+		require.NoError(t, repos.Accounts().Save(t.Context(), account))
+
+		// The transaction reads its own writes
+		account, err = repos.Accounts().Find(t.Context(), "acc1")
+		require.NoError(t, err)
+		require.Equal(t, 70, account.Balance)
+
+		repos.AfterCommit(func() { committed = true })
+		require.False(t, committed)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, committed)
+	require.Equal(t, 70, findAccount(t, unitOfWork, "acc1").Balance)
+}
+
+func TestUnitOfWork_Rollback(t *testing.T) {
+	unitOfWork := memory.NewUnitOfWork(memory.NewStore())
+	saveAccount(t, unitOfWork, &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"})
+
+	var committed bool
+	err := unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(t.Context(), "acc1")
+		require.NoError(t, err)
+		require.NoError(t, banking.Withdraw(account, 30))
+		require.NoError(t, repos.Accounts().Save(t.Context(), account))
+		require.NoError(t, repos.Transfers().SaveTransfer(t.Context(), banking.NewMoneyTransfer("acc1", "acc2", banking.NewMoney(30, "EUR"))))
+
+		repos.AfterCommit(func() { committed = true })
+		return errAborted
+	})
+	require.ErrorIs(t, err, errAborted)
+	require.False(t, committed)
+	require.Equal(t, 100, findAccount(t, unitOfWork, "acc1").Balance)
+}
+
+func TestUnitOfWork_Canceled(t *testing.T) {
+	unitOfWork := memory.NewUnitOfWork(memory.NewStore())
+	saveAccount(t, unitOfWork, &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	err := unitOfWork.WithinTx(ctx, func(repos usecases.Repositories) error {
+		account, err := repos.Accounts().Find(ctx, "acc1")
+		require.NoError(t, err)
+		require.NoError(t, banking.Deposit(account, 50))
+		cancel()
+		return repos.Accounts().Save(ctx, account)
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 100, findAccount(t, unitOfWork, "acc1").Balance)
+}
+
+func TestUnitOfWork_TransferMoney(t *testing.T) {
+	unitOfWork := memory.NewUnitOfWork(memory.NewStore())
+	saveAccount(t, unitOfWork, &banking.Account{ID: "acc1", Balance: 100, Currency: "EUR"})
+	saveAccount(t, unitOfWork, &banking.Account{ID: "acc2", Balance: 50, Currency: "EUR"})
+	transferMoney := usecases.NewTransferMoneyUseCase(unitOfWork, nil, nil)
+
+	_, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(30, "EUR"))
+	require.NoError(t, err)
+
+	failed, err := transferMoney.Execute(t.Context(), "acc1", "acc2", banking.NewMoney(500, "EUR"))
+	require.ErrorIs(t, err, banking.ErrInsufficientFunds)
+
+	require.Equal(t, 70, findAccount(t, unitOfWork, "acc1").Balance)
+	require.Equal(t, 80, findAccount(t, unitOfWork, "acc2").Balance)
+
+	// The failed transfer is recorded, without moving any money
+	err = unitOfWork.WithinTx(t.Context(), func(repos usecases.Repositories) error {
+		transfer, err := repos.Transfers().FindTransfer(t.Context(), failed.ID)
+		require.NoError(t, err)
+		require.Equal(t, banking.TransferFailed, transfer.Status)
+		return nil
+	})
+	require.NoError(t, err)
+}
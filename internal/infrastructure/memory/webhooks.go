@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+// WebhookRepository reads the webhooks and deliveries committed to a Store.
+// Its writes run in a transaction of their own.
+type WebhookRepository struct {
+	store *Store
+}
+
+// view reads the committed data through a transaction that never commits
+func (r *WebhookRepository) view() txWebhooks {
+	return txWebhooks{newTx(r.store)}
+}
+
+func (r *WebhookRepository) FindWebhook(ctx context.Context, id string) (*usecases.Webhook, error) {
+	return r.view().FindWebhook(ctx, id)
+}
+
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) ([]*usecases.Webhook, error) {
+	return r.view().ListWebhooks(ctx)
+}
+
+func (r *WebhookRepository) SaveWebhook(ctx context.Context, webhook *usecases.Webhook) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.Webhooks().SaveWebhook(ctx, webhook)
+	})
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.Webhooks().DeleteWebhook(ctx, id)
+	})
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*usecases.WebhookDelivery, error) {
+	return r.view().FindDelivery(ctx, id)
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, status usecases.DeliveryStatus, limit int) ([]*usecases.WebhookDelivery, error) {
+	return r.view().ListDeliveries(ctx, webhookID, status, limit)
+}
+
+func (r *WebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*usecases.WebhookDelivery, error) {
+	return r.view().DueDeliveries(ctx, now, limit)
+}
+
+func (r *WebhookRepository) SaveDelivery(ctx context.Context, delivery *usecases.WebhookDelivery) error {
+	return NewUnitOfWork(r.store).WithinTx(ctx, func(repos usecases.Repositories) error {
+		return repos.WebhookDeliveries().SaveDelivery(ctx, delivery)
+	})
+}
+
+func NewWebhookRepository(store *Store) *WebhookRepository {
+	return &WebhookRepository{store: store}
+}
+
+type txWebhooks struct{ *tx }
+
+func (t txWebhooks) FindWebhook(ctx context.Context, id string) (*usecases.Webhook, error) {
+	webhook, ok := t.visibleWebhooks()[id]
+	if !ok {
+		return nil, usecases.ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+func (t txWebhooks) ListWebhooks(ctx context.Context) ([]*usecases.Webhook, error) {
+	return slices.SortedFunc(maps.Values(t.visibleWebhooks()), func(a, b *usecases.Webhook) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	}), nil
+}
+
+func (t txWebhooks) SaveWebhook(ctx context.Context, webhook *usecases.Webhook) error {
+	saved := *webhook
+	saved.EventTypes = slices.Clone(webhook.EventTypes)
+	t.webhooks[webhook.ID] = saved
+	delete(t.deletedWebhooks, webhook.ID)
+	return nil
+}
+
+func (t txWebhooks) DeleteWebhook(ctx context.Context, id string) error {
+	if _, ok := t.visibleWebhooks()[id]; !ok {
+		return usecases.ErrWebhookNotFound
+	}
+	delete(t.webhooks, id)
+	t.deletedWebhooks[id] = true
+	return nil
+}
+
+func (t txWebhooks) FindDelivery(ctx context.Context, id string) (*usecases.WebhookDelivery, error) {
+	delivery, ok := t.visibleDeliveries()[id]
+	if !ok {
+		return nil, usecases.ErrDeliveryNotFound
+	}
+	return delivery, nil
+}
+
+func (t txWebhooks) ListDeliveries(ctx context.Context, webhookID string, status usecases.DeliveryStatus, limit int) ([]*usecases.WebhookDelivery, error) {
+	deliveries := slices.SortedFunc(maps.Values(t.visibleDeliveries()), func(a, b *usecases.WebhookDelivery) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	deliveries = slices.DeleteFunc(deliveries, func(delivery *usecases.WebhookDelivery) bool {
+		return delivery.WebhookID != webhookID || (status != "" && delivery.Status != status)
+	})
+	return deliveries[:min(limit, len(deliveries))], nil
+}
+
+func (t txWebhooks) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*usecases.WebhookDelivery, error) {
+	deliveries := slices.SortedFunc(maps.Values(t.visibleDeliveries()), func(a, b *usecases.WebhookDelivery) int {
+		return cmp.Or(a.NextAttemptAt.Compare(b.NextAttemptAt), cmp.Compare(a.ID, b.ID))
+	})
+	deliveries = slices.DeleteFunc(deliveries, func(delivery *usecases.WebhookDelivery) bool {
+		return delivery.Status != usecases.DeliveryPending || delivery.NextAttemptAt.After(now)
+	})
+	return deliveries[:min(limit, len(deliveries))], nil
+}
+
+func (t txWebhooks) SaveDelivery(ctx context.Context, delivery *usecases.WebhookDelivery) error {
+	t.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// visibleWebhooks returns copies of the webhooks as seen by the transaction
+func (t txWebhooks) visibleWebhooks() map[string]*usecases.Webhook {
+	t.store.mu.RLock()
+	merged := maps.Clone(t.store.webhooks)
+	t.store.mu.RUnlock()
+	maps.Copy(merged, t.webhooks)
+
+	webhooks := make(map[string]*usecases.Webhook, len(merged))
+	for id, webhook := range merged {
+		if !t.deletedWebhooks[id] {
+			webhook.EventTypes = slices.Clone(webhook.EventTypes)
+			webhooks[id] = &webhook
+		}
+	}
+	return webhooks
+}
+
+// visibleDeliveries returns copies of the deliveries as seen by the transaction,
+// without those of deleted webhooks
+func (t txWebhooks) visibleDeliveries() map[string]*usecases.WebhookDelivery {
+	t.store.mu.RLock()
+	merged := maps.Clone(t.store.deliveries)
+	t.store.mu.RUnlock()
+	maps.Copy(merged, t.deliveries)
+
+	deliveries := make(map[string]*usecases.WebhookDelivery, len(merged))
+	for id, delivery := range merged {
+		if !t.deletedWebhooks[delivery.WebhookID] {
+			deliveries[id] = &delivery
+		}
+	}
+	return deliveries
+}
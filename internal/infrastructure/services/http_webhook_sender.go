@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+// Headers of the requests posted to webhooks
+const (
+	HeaderWebhookID        = "Webhook-Id"
+	HeaderWebhookEvent     = "Webhook-Event"
+	HeaderWebhookTimestamp = "Webhook-Timestamp"
+	HeaderWebhookSignature = "Webhook-Signature"
+)
+
+// SignWebhook returns the signature of a payload sent at timestamp, in Unix
+// seconds: the hex HMAC-SHA256, keyed by the secret of the webhook, of the
+// timestamp, a dot and the payload. Signing the timestamp lets receivers
+// refuse old requests replayed to them.
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// HTTPWebhookSender implements usecases.WebhookSender by posting the payload
+// of a delivery as JSON, signed in the Webhook-Signature header. Any 2xx
+// answer acknowledges it. Unless private networks are allowed, it only
+// connects to usecases.PublicIP addresses, whatever the names of the webhooks
+// and the redirects they answer with resolve to.
+type HTTPWebhookSender struct {
+	client *http.Client
+}
+
+func (s *HTTPWebhookSender) Send(ctx context.Context, webhook *usecases.Webhook, delivery *usecases.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderWebhookID, delivery.ID)
+	req.Header.Set(HeaderWebhookEvent, delivery.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// dialPublic refuses to connect to addresses that are not public, once
+// their name is resolved
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !usecases.PublicIP(ip) {
+		return fmt.Errorf("%w: %s", usecases.ErrWebhookHostForbidden, host)
+	}
+	return nil
+}
+
+// NewHTTPWebhookSender gives up on a webhook that takes longer than timeout
+// to answer, and on webhooks in private networks unless allowPrivateNetworks
+func NewHTTPWebhookSender(timeout time.Duration, allowPrivateNetworks bool) *HTTPWebhookSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateNetworks {
+		// A proxy would connect on our behalf, past the check
+		dialer.Control = dialPublic
+		transport.Proxy = nil
+	}
+	transport.DialContext = dialer.DialContext
+	return &HTTPWebhookSender{client: &http.Client{Timeout: timeout, Transport: transport}}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+)
+
+// Handler receives the events published in process. An error makes the
+// relay publish the event again later.
+type Handler func(ctx context.Context, message usecases.OutboxMessage) error
+
+// InProcessPublisher implements usecases.EventPublisher by handing every
+// event to the handlers subscribed in the same process, one at a time
+type InProcessPublisher struct {
+	mu       sync.Mutex
+	handlers []Handler
+}
+
+func (p *InProcessPublisher) Subscribe(handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, handler)
+}
+
+func (p *InProcessPublisher) Publish(ctx context.Context, message usecases.OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, handler := range p.handlers {
+		if err := handler(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{}
+}
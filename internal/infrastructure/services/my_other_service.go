@@ -1,3 +0,0 @@
-package services
-
-// A client to an external service
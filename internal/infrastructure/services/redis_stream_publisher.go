@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	usecases "github.com/ppicom/newtonian/internal/application/use_cases"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamPublisher implements usecases.EventPublisher by appending every
+// event to a single Redis stream, which keeps them in the order they were
+// published. Consumers drop the copies of an event, identified by its
+// event_id, that at-least-once delivery lets through.
+type RedisStreamPublisher struct {
+	redis  *redis.Client
+	stream string
+}
+
+func (p *RedisStreamPublisher) Publish(ctx context.Context, message usecases.OutboxMessage) error {
+	return p.redis.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		Values: []any{
+			"event_id", message.EventID,
+			"event_type", message.EventType,
+			"ordering_key", message.OrderingKey,
+			"occurred_at", message.OccurredAt.UTC().Format(time.RFC3339Nano),
+			"payload", message.Payload,
+		},
+	}).Err()
+}
+
+func NewRedisStreamPublisher(redis *redis.Client, stream string) *RedisStreamPublisher {
+	return &RedisStreamPublisher{
+		redis:  redis,
+		stream: stream,
+	}
+}